@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	CookieName = "token"       // имя cookie, в которой веб-интерфейс хранит токен
+	TokenTTL   = 8 * time.Hour // срок действия токена, как у cookie в login.html
+)
+
+const AdminID int64 = 0 // пользователь, входящий по паролю TODO_PASSWORD, ему принадлежат задачи однопользовательской версии
+
+var ErrInvalidToken = errors.New("invalid token")
+
+func Password() string { // пароль из переменной окружения TODO_PASSWORD
+	return os.Getenv("TODO_PASSWORD")
+}
+
+func MultiUser() bool { // TODO_MULTIUSER - регистрация пользователей, у каждого свой список задач
+	switch os.Getenv("TODO_MULTIUSER") {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+func Enabled() bool { // без пароля и многопользовательского режима аутентификация отключена
+	return Password() != "" || MultiUser()
+}
+
+// passwordMAC - отпечаток секрета в токене, чтобы после смены пароля старые токены не принимались.
+// Это HMAC под ключом подписи: без ключа по токену пароль не подобрать.
+func passwordMAC(secret string) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	storedKey     []byte
+	randomKey     []byte
+	randomKeyOnce sync.Once
+)
+
+// SetSigningKey задает ключ подписи, сохраненный в бд, чтобы токены действовали после перезапуска сервера.
+func SetSigningKey(key []byte) {
+	storedKey = key
+}
+
+// signingKey - ключ подписи: TODO_JWT_SECRET, ключ из бд или случайный ключ до перезапуска сервера.
+// Пароль TODO_PASSWORD ключом не служит, а в токен попадает только его HMAC под этим ключом.
+func signingKey() []byte {
+	if secret := os.Getenv("TODO_JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	if storedKey != nil {
+		return storedKey
+	}
+	randomKeyOnce.Do(func() {
+		randomKey = make([]byte, 32)
+		if _, err := rand.Read(randomKey); err != nil {
+			panic(err)
+		}
+	})
+	return randomKey
+}
+
+type claims struct {
+	UserID      int64  `json:"uid"`
+	PasswordMAC string `json:"pwd"`
+	jwt.RegisteredClaims
+}
+
+// NewToken возвращает подписанный JWT пользователя. secret - пароль TODO_PASSWORD для AdminID
+// или хэш пароля из таблицы users для зарегистрированных пользователей.
+func NewToken(userID int64, secret string) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		UserID:      userID,
+		PasswordMAC: passwordMAC(secret),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenTTL)),
+		},
+	})
+	return token.SignedString(signingKey())
+}
+
+// Users - источник секретов зарегистрированных пользователей для проверки токенов.
+type Users interface {
+	Secret(userID int64) (string, error)
+}
+
+func CheckToken(tokenString string, users Users) (int64, error) { // возвращает пользователя токена
+	var c claims
+	token, err := jwt.ParseWithClaims(tokenString, &c, func(token *jwt.Token) (interface{}, error) {
+		return signingKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return 0, ErrInvalidToken
+	}
+
+	var secret string
+	if c.UserID == AdminID {
+		secret = Password()
+	} else if users != nil && MultiUser() {
+		secret, err = users.Secret(c.UserID)
+	}
+	if err != nil || secret == "" { // пользователь удален или вход по паролю отключен
+		return 0, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(c.PasswordMAC), []byte(passwordMAC(secret))) { // пароль сменился после выдачи токена
+		return 0, ErrInvalidToken
+	}
+	return c.UserID, nil
+}
+
+type contextKey struct{}
+
+func WithUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+func UserID(ctx context.Context) int64 { // пользователь запроса, AdminID если аутентификация отключена
+	userID, _ := ctx.Value(contextKey{}).(int64)
+	return userID
+}
+
+// Tokens - источник личных API-токенов, передаваемых в заголовке Authorization: Bearer.
+type Tokens interface {
+	Authenticate(token string) (userID int64, readOnly bool, err error)
+}
+
+const APITokenPrefix = "todo_" // префикс личных API-токенов, отличает их от JWT
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(header[len("Bearer "):])
+	}
+	return ""
+}
+
+func readOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// Middleware пропускает запрос только с действующим токеном: JWT в cookie или в заголовке
+// Authorization: Bearer, либо личным API-токеном в том же заголовке.
+// Токен только для чтения допускает лишь GET-запросы.
+func Middleware(users Users, tokens Tokens) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Enabled() { // аутентификация не требуется, все задачи общие
+				next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), AdminID)))
+				return
+			}
+			userID, readOnly, err := int64(0), false, ErrInvalidToken
+			if token := bearerToken(r); strings.HasPrefix(token, APITokenPrefix) {
+				userID, readOnly, err = tokens.Authenticate(token)
+				if err == nil && userID != AdminID && !MultiUser() { // многопользовательский режим выключен
+					err = ErrInvalidToken
+				}
+			} else if token != "" {
+				userID, err = CheckToken(token, users)
+			} else if cookie, cookieErr := r.Cookie(CookieName); cookieErr == nil {
+				userID, err = CheckToken(cookie.Value, users)
+			}
+			if err != nil {
+				http.Error(w, `{"error": "Authentification required"}`, http.StatusUnauthorized)
+				return
+			}
+			if readOnly && !readOnlyMethod(r.Method) {
+				http.Error(w, `{"error": "Read-only token"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), userID)))
+		})
+	}
+}
+
+// AdminOnly пропускает в многопользовательском режиме только администратора (вход по TODO_PASSWORD).
+// Нужен для общих для всех пользователей настроек, например праздников.
+func AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if MultiUser() && UserID(r.Context()) != AdminID {
+			http.Error(w, `{"error": "Insufficient permissions"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
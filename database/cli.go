@@ -0,0 +1,48 @@
+package database
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MigrateCommand - команда "migrate [-dry-run]": применяет миграции без запуска сервера.
+// С -dry-run только выводит шаги, которые будут выполнены. Возвращает код завершения.
+func MigrateCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	dryRun := flags.Bool("dry-run", false, "print pending migrations without applying them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if _, err := os.Stat(Path()); os.IsNotExist(err) && *dryRun { // пробный запуск не создает файл бд
+		migrations, err := Migrations()
+		if err != nil {
+			fmt.Fprintln(out, err)
+			return 1
+		}
+		for _, migration := range migrations {
+			fmt.Fprintf(out, "pending migration %s\n", migration)
+		}
+		return 0
+	}
+
+	db, err := Open()
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	defer db.Close()
+
+	migrations, err := Migrate(db, *dryRun, out)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 1
+	}
+	if len(migrations) == 0 {
+		fmt.Fprintln(out, "schema is up to date")
+	}
+	return 0
+}
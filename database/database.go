@@ -2,54 +2,56 @@ package database
 
 import (
 	"database/sql"
+	"io"
 	"log"
 	"os"
 
 	_ "modernc.org/sqlite" // импорт драйвера SQLite
 )
 
-func ConnectDB() (*sql.DB, error) {
+func Path() string { // путь к файлу бд: TODO_DBFILE или scheduler.db
 	var nameDB = "scheduler.db"
 	path := os.Getenv("TODO_DBFILE")
 	if path == "" {
 		path = nameDB
 	}
+	return path
+}
 
-	var install bool
-	if _, err := os.Stat(path); os.IsNotExist(err) { // проверка существования файла базы данных
-		install = true                     // если файл базы данных не существует (true), то создаем файл через sql запросы из createTableAndIndex
-		fileCreate, err := os.Create(path) // создается пустой файл бд
-		if err != nil {
-			return nil, err
-		}
-		fileCreate.Close()
+func Open() (*DB, error) { // подключение к файлу бд без миграций
+	db, err := sql.Open("sqlite", Path()) // подключение к БД (не работает с sqlite3)
+	if err != nil {
+		return nil, err
 	}
+	return &DB{DB: db}, nil
+}
 
-	db, err := sql.Open("sqlite", path) // подключение к БД (не работает с sqlite3)
+func OpenMemory() (*DB, error) { // бд SQLite в памяти со всеми миграциями, данные пропадают при закрытии
+	db, err := sql.Open("sqlite", ":memory:")
 	if err != nil {
-		log.Fatalf("Ошибка при подключении к базе данных: %v", err)
+		return nil, err
 	}
-	if install { // создание таблицы и индекса если надо
-		createTableAndIndex(db)
+	db.SetMaxOpenConns(1) // у каждого подключения к :memory: своя бд
+	memory := &DB{DB: db}
+	if _, err := Migrate(memory, false, io.Discard); err != nil {
+		db.Close()
+		return nil, err
 	}
-	return db, nil
+	return memory, nil
 }
 
-func createTableAndIndex(db *sql.DB) {
-	sqlStmt := `
-	CREATE TABLE scheduler (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		date CHAR(8) NOT NULL DEFAULT '',
-		title VARCHAR(256) NOT NULL DEFAULT '',
-		comment TEXT NOT NULL DEFAULT '',
-		repeat VARCHAR(128) NOT NULL DEFAULT ''
-	);
-	CREATE INDEX idx_date ON scheduler(date);
-`
-	_, err := db.Exec(sqlStmt)
+func ConnectDB() (*DB, error) { // подключение к бд и применение новых миграций
+	db, err := Open()
 	if err != nil {
-		log.Fatalf("Ошибка при создании таблицы или индекса: %v", err)
-	} else {
-		log.Println("Таблица и индекс успешно созданы.")
+		return nil, err
 	}
+	migrations, err := Migrate(db, false, io.Discard)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, migration := range migrations {
+		log.Printf("Применена миграция %s", migration)
+	}
+	return db, nil
 }
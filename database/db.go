@@ -0,0 +1,21 @@
+package database
+
+import "database/sql"
+
+// DB - подключение к бд. Begin возвращает Tx, чтобы функции хранилища принимали
+// транзакцию этого пакета, а не *sql.Tx.
+type DB struct {
+	*sql.DB
+}
+
+type Tx struct {
+	*sql.Tx
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
@@ -0,0 +1,49 @@
+package database
+
+type legacyColumn struct {
+	name       string
+	definition string
+}
+
+// legacyColumns - колонки scheduler, которые до появления миграций добавлялись при запуске сервера.
+// В базах, созданных раньше, части из них может не быть; они добавляются перед базовой миграцией.
+// Новые колонки сюда не добавляются - для них пишется миграция.
+var legacyColumns = []legacyColumn{
+	{"repeat_until", "CHAR(8) NOT NULL DEFAULT ''"},            // повторять до даты включительно
+	{"repeat_count", "INTEGER NOT NULL DEFAULT 0"},             // сколько раз осталось выполнить, 0 - без ограничения
+	{"repeat_mode", "VARCHAR(16) NOT NULL DEFAULT 'schedule'"}, // от чего отсчитывается следующая дата
+	{"user_id", "INTEGER NOT NULL DEFAULT 0"},                  // владелец задачи, 0 - вход по TODO_PASSWORD или без аутентификации
+	{"list_id", "INTEGER NOT NULL DEFAULT 0"},                  // общий список, 0 - личная задача
+}
+
+func missingLegacyColumns(db *DB) ([]legacyColumn, error) { // пусто, если таблицы scheduler еще нет
+	exists, err := tableExists(db, "scheduler")
+	if err != nil || !exists {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT name FROM pragma_table_info('scheduler')")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []legacyColumn
+	for _, column := range legacyColumns {
+		if !existing[column.name] {
+			missing = append(missing, column)
+		}
+	}
+	return missing, nil
+}
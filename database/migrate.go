@@ -0,0 +1,169 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration - шаг изменения схемы из файла migrations/NNNN_name.sql.
+// Примененные шаги записываются в таблицу schema_version и повторно не выполняются,
+// поэтому уже выпущенные файлы не меняют: новая колонка или таблица - новый файл.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+const baselineVersion = 1 // миграция, до которой доводятся базы, созданные до появления миграций
+
+const createVersionTable = `CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name VARCHAR(256) NOT NULL DEFAULT '',
+	applied_at VARCHAR(32) NOT NULL DEFAULT ''
+)`
+
+func Migrations() ([]Migration, error) { // встроенные миграции по возрастанию версии
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		number, title, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+		data, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: title, SQL: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Migrate применяет миграции, которых нет в schema_version, каждую в своей транзакции,
+// и возвращает их список. В режиме dryRun бд не меняется, шаги только выводятся в out.
+func Migrate(db *DB, dryRun bool, out io.Writer) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	var legacyColumns []legacyColumn // база создана до появления миграций
+	if len(applied) == 0 {
+		if legacyColumns, err = missingLegacyColumns(db); err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		for _, migration := range pending {
+			fmt.Fprintf(out, "pending migration %s\n", migration)
+			if migration.Version == baselineVersion {
+				for _, column := range legacyColumns {
+					fmt.Fprintf(out, "\tALTER TABLE scheduler ADD COLUMN %s %s\n", column.name, column.definition)
+				}
+			}
+		}
+		return pending, nil
+	}
+
+	if _, err := db.Exec(createVersionTable); err != nil {
+		return nil, err
+	}
+	for _, migration := range pending {
+		if err := apply(db, migration, legacyColumns); err != nil {
+			return nil, fmt.Errorf("migration %s: %w", migration, err)
+		}
+		fmt.Fprintf(out, "applied migration %s\n", migration)
+	}
+	return pending, nil
+}
+
+func apply(db *DB, migration Migration, legacyColumns []legacyColumn) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if migration.Version == baselineVersion {
+		for _, column := range legacyColumns {
+			if _, err := tx.Exec("ALTER TABLE scheduler ADD COLUMN " + column.name + " " + column.definition); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.Exec(migration.SQL); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+		migration.Version, migration.Name, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func tableExists(db *DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	return count > 0, err
+}
+
+func appliedVersions(db *DB) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	exists, err := tableExists(db, "schema_version")
+	if err != nil || !exists {
+		return applied, err
+	}
+	rows, err := db.Query("SELECT version FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
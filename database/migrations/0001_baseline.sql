@@ -0,0 +1,62 @@
+-- Схема на момент появления миграций. Базы, созданные раньше, доводятся до нее
+-- добавлением колонок legacyColumns (missingLegacyColumns в database/legacy.go) перед этой миграцией,
+-- после чего этот файл только создает недостающее.
+CREATE TABLE IF NOT EXISTS scheduler (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	date CHAR(8) NOT NULL DEFAULT '',
+	title VARCHAR(256) NOT NULL DEFAULT '',
+	comment TEXT NOT NULL DEFAULT '',
+	repeat VARCHAR(128) NOT NULL DEFAULT '',
+	repeat_until CHAR(8) NOT NULL DEFAULT '',
+	repeat_count INTEGER NOT NULL DEFAULT 0,
+	repeat_mode VARCHAR(16) NOT NULL DEFAULT 'schedule',
+	user_id INTEGER NOT NULL DEFAULT 0,
+	list_id INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
+CREATE INDEX IF NOT EXISTS idx_user_date ON scheduler(user_id, date);
+CREATE INDEX IF NOT EXISTS idx_list_date ON scheduler(list_id, date);
+
+-- даты, пропускаемые при переносе повторяющейся задачи
+CREATE TABLE IF NOT EXISTS exdates (
+	task_id INTEGER NOT NULL,
+	date CHAR(8) NOT NULL,
+	PRIMARY KEY (task_id, date)
+);
+
+-- праздники для правил с модификатором рабочих дней
+CREATE TABLE IF NOT EXISTS holidays (
+	date CHAR(8) PRIMARY KEY,
+	name VARCHAR(256) NOT NULL DEFAULT ''
+);
+
+-- пользователи многопользовательского режима
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	login VARCHAR(64) NOT NULL UNIQUE,
+	password_hash VARCHAR(128) NOT NULL
+);
+
+-- личные API-токены, хранится только sha256 токена
+CREATE TABLE IF NOT EXISTS api_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL DEFAULT 0,
+	name VARCHAR(128) NOT NULL DEFAULT '',
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	read_only INTEGER NOT NULL DEFAULT 0,
+	created VARCHAR(32) NOT NULL DEFAULT '',
+	last_used VARCHAR(32) NOT NULL DEFAULT ''
+);
+
+-- общие списки задач и их участники: viewer, editor или owner
+CREATE TABLE IF NOT EXISTS lists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(256) NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS list_members (
+	list_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	role VARCHAR(16) NOT NULL DEFAULT 'viewer',
+	PRIMARY KEY (list_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_member_user ON list_members(user_id);
@@ -0,0 +1,26 @@
+-- полнотекстовый поиск по заголовку и комментарию задачи.
+-- unicode61 приводит к нижнему регистру любые буквы, а не только латиницу, как LIKE;
+-- индекс хранит только токены (content='scheduler') и обновляется триггерами
+CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+	title,
+	comment,
+	content='scheduler',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+	INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+	INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+END;
+
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+	INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+	INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+
+-- индекс для задач, созданных до этой миграции
+INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild');
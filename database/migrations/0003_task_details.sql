@@ -0,0 +1,12 @@
+-- приоритет, время начала и длительность задачи
+ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scheduler ADD COLUMN start_time VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE scheduler ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
+
+-- метки задач, в нижнем регистре без #
+CREATE TABLE IF NOT EXISTS tags (
+	task_id INTEGER NOT NULL,
+	tag VARCHAR(32) NOT NULL,
+	PRIMARY KEY (task_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_tag ON tags(tag);
@@ -0,0 +1,13 @@
+-- подзадачи: родительская задача, 0 - задача верхнего уровня
+ALTER TABLE scheduler ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_parent ON scheduler(parent_id);
+
+-- пункты чек-листа внутри задачи, done хранится числом 0/1
+CREATE TABLE IF NOT EXISTS checklist (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	text VARCHAR(512) NOT NULL DEFAULT '',
+	done INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_checklist_task ON checklist(task_id, position);
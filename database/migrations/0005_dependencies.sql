@@ -0,0 +1,7 @@
+-- зависимости: задача task_id заблокирована задачей blocker_id
+CREATE TABLE IF NOT EXISTS dependencies (
+	task_id INTEGER NOT NULL,
+	blocker_id INTEGER NOT NULL,
+	PRIMARY KEY (task_id, blocker_id)
+);
+CREATE INDEX IF NOT EXISTS idx_dependency_blocker ON dependencies(blocker_id);
@@ -0,0 +1,14 @@
+-- выполнения задач: задача могла быть удалена, поэтому заголовок, список и владелец копируются
+CREATE TABLE IF NOT EXISTS history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL DEFAULT 0,
+	list_id INTEGER NOT NULL DEFAULT 0,
+	title VARCHAR(256) NOT NULL DEFAULT '',
+	date CHAR(8) NOT NULL DEFAULT '',
+	completed_at VARCHAR(32) NOT NULL DEFAULT '',
+	note TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_history_task ON history(task_id);
+CREATE INDEX IF NOT EXISTS idx_history_user ON history(user_id);
+CREATE INDEX IF NOT EXISTS idx_history_list ON history(list_id);
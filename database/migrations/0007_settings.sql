@@ -0,0 +1,5 @@
+-- настройки сервера, которые должны переживать перезапуск, например ключ подписи токенов
+CREATE TABLE IF NOT EXISTS settings (
+	name VARCHAR(64) PRIMARY KEY,
+	value TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,2 @@
+-- дата серии повторений до переноса на рабочий день (+wd/-wd), пусто - совпадает с date
+ALTER TABLE scheduler ADD COLUMN series_date VARCHAR(8) NOT NULL DEFAULT '';
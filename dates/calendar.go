@@ -0,0 +1,89 @@
+package dates
+
+import (
+	"sync"
+	"time"
+)
+
+// Calendar - производственный календарь: выходные дни недели и праздники.
+type Calendar struct {
+	mu       sync.RWMutex
+	holidays map[string]string // 20060102 -> название праздника
+	weekend  map[time.Weekday]bool
+}
+
+func NewCalendar() *Calendar {
+	return &Calendar{
+		holidays: make(map[string]string),
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+	}
+}
+
+// WorkCalendar - календарь, по которому правила с модификатором +wd/-wd переносят даты.
+// Праздники в него загружаются при запуске сервера и при изменении через API.
+var WorkCalendar = NewCalendar()
+
+func (c *Calendar) SetHolidays(holidays map[string]string) { // полностью заменяет список праздников
+	copied := make(map[string]string, len(holidays))
+	for date, name := range holidays {
+		copied[date] = name
+	}
+	c.mu.Lock()
+	c.holidays = copied
+	c.mu.Unlock()
+}
+
+func (c *Calendar) IsWorkday(date time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.weekend[date.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[date.Format(DefaultDateFormat)]
+	return !holiday
+}
+
+func (c *Calendar) Shift(date time.Time, direction int) time.Time { // ближайший рабочий день вперед (1) или назад (-1)
+	for i := 0; i < maxSearchDays && !c.IsWorkday(date); i++ {
+		date = date.AddDate(0, 0, direction)
+	}
+	return date
+}
+
+const (
+	workdayForward  = "+wd"
+	workdayBackward = "-wd"
+	workdayAlias    = "workdays" // то же, что +wd: "d 1 workdays" - каждый рабочий день
+)
+
+// WorkdayRule - правило с модификатором рабочих дней, например "m 25 -wd".
+// Если дата по правилу Rule выпадает на выходной или праздник, она переносится
+// на ближайший рабочий день вперед (Direction = 1) или назад (Direction = -1).
+// Next отсчитывает правило от after; у задачи следующую дату считает NextSeriesDate
+// от даты серии до переноса, чтобы перенос не сдвигал следующие даты.
+type WorkdayRule struct {
+	Rule      Rule
+	Direction int
+	Calendar  *Calendar
+}
+
+func (r WorkdayRule) Next(after time.Time) time.Time {
+	date := after
+	for i := 0; i < maxSearchDays; i++ {
+		date = r.Rule.Next(date)
+		if date.IsZero() {
+			return date
+		}
+		if shifted := r.Calendar.Shift(date, r.Direction); shifted.After(after) { // перенос назад может попасть на уже пройденную дату
+			return shifted
+		}
+	}
+	return time.Time{}
+}
+
+func (r WorkdayRule) String() string {
+	if r.Direction < 0 {
+		return r.Rule.String() + " " + workdayBackward
+	}
+	return r.Rule.String() + " " + workdayForward
+}
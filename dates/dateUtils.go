@@ -3,74 +3,134 @@ package dates
 import (
 	"errors"
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 )
 
 const DefaultDateFormat = "20060102" // дефолтный формат
 
-func isLeapYear(year int) bool { // проверка високосного
-	if year%4 == 0 {
-		if year%100 == 0 {
-			return year%400 == 0
-		}
-		return true
-	}
-	return false
-}
+var ErrNoNextDate = errors.New("the repeat rule has no more dates") // серия повторений закончилась
 
 func NextDate(now time.Time, dateStr string, repeat string) (string, error) {
-	date, err := time.Parse(DefaultDateFormat, dateStr) // парсинг исходной даты
+	nextDate, _, err := NextDateWithRule(now, dateStr, repeat)
+	return nextDate, err
+}
+
+// NextDateWithRule вычисляет следующую дату так же, как NextDate, и дополнительно
+// возвращает правило, которое нужно сохранить у задачи для следующей даты:
+// у RRULE с COUNT уменьшается число оставшихся повторений.
+func NextDateWithRule(now time.Time, dateStr string, repeat string) (string, string, error) {
+	return NextDateExcluding(now, dateStr, repeat, nil)
+}
+
+// NextDateExcluding работает как NextDateWithRule, но пропускает даты из excluded (в формате 20060102).
+// Пропущенные даты, как и EXDATE в RFC 5545, учитываются в COUNT правила RRULE.
+func NextDateExcluding(now time.Time, dateStr string, repeat string, excluded []string) (string, string, error) {
+	nextDate, _, nextRepeat, err := NextSeriesDate(now, dateStr, "", repeat, excluded)
+	return nextDate, nextRepeat, err
+}
+
+// NextSeriesDate работает как NextDateExcluding для задачи, дата которой могла быть перенесена
+// модификатором +wd/-wd: series - дата серии до переноса (пусто - совпадает с dateStr).
+// Правило отсчитывается от даты серии, на рабочий день переносится только результат, поэтому
+// перенос не сдвигает следующие даты. Вторым значением возвращается новая дата серии.
+func NextSeriesDate(now time.Time, dateStr string, series string, repeat string, excluded []string) (string, string, string, error) {
+	skip := make(map[string]bool, len(excluded))
+	for _, date := range excluded {
+		skip[date] = true
+	}
+
+	current, err := time.Parse(DefaultDateFormat, dateStr) // парсинг исходной даты
 	if err != nil {
-		return "", fmt.Errorf("invalid date format: %v", err) // возврат ошибки если формат даты неверный
+		return "", "", "", fmt.Errorf("invalid date format: %v", err) // возврат ошибки если формат даты неверный
 	}
 
-	if repeat == "" { // если правило повторения пустое то вернем ошибку
-		return "", errors.New("repeat is required")
+	rule, err := ParseRule(repeat) // разбор правила повторения, пустое правило тоже ошибка
+	if err != nil {
+		return "", "", "", err
 	}
 
-	repeatParts := strings.Split(repeat, " ") // разделил правило повторения на части
-	if len(repeatParts) == 0 {
-		return "", errors.New("invalid repeat rule format")
+	workday, shifted := rule.(WorkdayRule)
+	counted, limited := rule.(RRule) // RRULE с COUNT, в том числе с модификатором рабочих дней
+	if shifted {
+		counted, limited = workday.Rule.(RRule)
 	}
-	rule := repeatParts[0]
-	switch rule {
-	case "d": // кол-во дней
-		if len(repeatParts) != 2 {
-			return "", errors.New("invalid repeat rule format for d")
+	limited = limited && counted.Count > 0
+
+	seriesDate := current
+	if shifted && series != "" {
+		seriesDate, err = time.Parse(DefaultDateFormat, series)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid series date format: %v", err)
 		}
-		days, err := strconv.Atoi(repeatParts[1]) // конвертация строки в число дней
-		if err != nil || days < 1 || days > 400 { // проверка соответсвия
-			return "", errors.New("invalid number of days")
+	}
+
+	for steps := 1; ; steps++ {
+		if shifted {
+			seriesDate = workday.Rule.Next(seriesDate) // переход к следующей дате серии по правилу без переноса
+		} else {
+			seriesDate = rule.Next(seriesDate) // переход к следующей дате по правилу
 		}
-		if dateStr == now.Format(DefaultDateFormat) {
-			date = now
+		if seriesDate.IsZero() {
+			return "", "", "", ErrNoNextDate
 		}
-		for {
-			date = date.AddDate(0, 0, days) // добавление дней к дате now
-			if date.After(now) {            // если новая дата после текущей, возвращаем её
-				return date.Format(DefaultDateFormat), nil
-			}
+		if limited && steps >= counted.Count { // исходная дата - первое повторение
+			return "", "", "", ErrNoNextDate
 		}
-	case "y": // ежегодно
-		if len(repeatParts) != 1 {
-			return "", errors.New("invalid repeat rule format for y")
+		date := seriesDate
+		if shifted {
+			date = workday.Calendar.Shift(seriesDate, workday.Direction)
 		}
-		for {
-			date = date.AddDate(1, 0, 0) // добавление гола
-			if date.After(now) {
-				if date.Month() == time.February && date.Day() == 29 && !isLeapYear(date.Year()) {
-					date = date.AddDate(0, 0, 1) // если следующий год не високосный то переходим на следующий день
+		// если новая дата после текущей и не исключена, возвращаем её; перенос назад может попасть на уже пройденную дату
+		if date.After(now) && date.After(current) && !skip[date.Format(DefaultDateFormat)] {
+			if limited {
+				counted.Count -= steps
+				rule = counted
+				if shifted {
+					workday.Rule = counted
+					rule = workday
 				}
-				return date.Format(DefaultDateFormat), nil
 			}
+			return date.Format(DefaultDateFormat), seriesDate.Format(DefaultDateFormat), rule.String(), nil
+		}
+	}
+}
+
+const MaxOccurrences = 100 // ограничение на количество дат в предпросмотре
+
+// Occurrences возвращает ближайшие даты повторения задачи так, как если бы её
+// отмечали выполненной в каждую из дат: каждая следующая дата считается через
+// NextSeriesDate от предыдущей, даты из excluded пропускаются. Перебор
+// останавливается после count дат (0 - без ограничения), после даты until
+// (пустая строка - без ограничения), по окончании серии или после MaxOccurrences дат.
+func Occurrences(now time.Time, dateStr string, repeat string, count int, until string, excluded []string) ([]string, error) {
+	var untilDate time.Time
+	if until != "" {
+		var err error
+		untilDate, err = time.Parse(DefaultDateFormat, until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until format: %v", err)
+		}
+	}
+	if count <= 0 || count > MaxOccurrences {
+		count = MaxOccurrences
+	}
+
+	occurrences := []string{}
+	series := ""
+	for len(occurrences) < count {
+		nextDate, nextSeries, nextRepeat, err := NextSeriesDate(now, dateStr, series, repeat, excluded)
+		if errors.Is(err, ErrNoNextDate) { // серия закончилась
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
-	case "": // пустое
-		if len(repeatParts) != 2 {
+		next, _ := time.Parse(DefaultDateFormat, nextDate)
+		if !untilDate.IsZero() && next.After(untilDate) {
+			break
 		}
-		return "", errors.New("invalid repeat rule format")
-	default:
-		return "", errors.New("unsupported repeat rule") // ошибка
+		occurrences = append(occurrences, nextDate)
+		now, dateStr, series, repeat = next, nextDate, nextSeries, nextRepeat // задача выполнена в срок
 	}
+	return occurrences, nil
 }
@@ -0,0 +1,309 @@
+package dates
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	LangRU = "ru"
+	LangEN = "en"
+)
+
+const describeDateFormat = "02.01.2006" // формат дат в описании, как в поиске задач
+
+var (
+	enWeekdays   = [...]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	ruWeekdays   = [...]string{"воскресенье", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу"}           // винительный падеж
+	ruWeekdaysPl = [...]string{"воскресеньям", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам"} // по понедельникам
+	ruWeekdayGen = [...]int{2, 0, 0, 1, 0, 1, 1}                                                                            // род: 0 - мужской, 1 - женский, 2 - средний
+
+	enMonths      = [...]string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	ruMonthsGen   = [...]string{"", "января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	ruMonthsPrep  = [...]string{"", "январе", "феврале", "марте", "апреле", "мае", "июне", "июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+	enOrdinals    = [...]string{"", "first", "second", "third", "fourth", "fifth"}
+	ruOrdinals    = [...][3]string{{}, {"первый", "первую", "первое"}, {"второй", "вторую", "второе"}, {"третий", "третью", "третье"}, {"четвертый", "четвертую", "четвертое"}, {"пятый", "пятую", "пятое"}}
+	ruLastOrdinal = [...][3]string{{"последний", "последнюю", "последнее"}, {"предпоследний", "предпоследнюю", "предпоследнее"}}
+)
+
+// Describe возвращает описание правила повторения на русском (LangRU) или английском (LangEN) языке,
+// например "m -1,15 3,6" - "on the 15th and the last day of March and June".
+func Describe(rule Rule, lang string) string {
+	en := lang == LangEN
+	switch r := rule.(type) {
+	case DailyRule:
+		if en {
+			return every(r.Days, "day", "days")
+		}
+		return ruEvery(r.Days, [3]string{"день", "дня", "дней"}, 0)
+	case YearlyRule:
+		if en {
+			return "every year"
+		}
+		return "каждый год"
+	case WeeklyRule:
+		days := make([]string, len(r.Days))
+		for i, day := range r.Days {
+			if en {
+				days[i] = enWeekdays[day%7]
+			} else {
+				days[i] = ruWeekdaysPl[day%7]
+			}
+		}
+		if en {
+			return "every " + joinWords(days, "and")
+		}
+		return "по " + joinWords(days, "и")
+	case MonthlyRule:
+		if en {
+			text := "on " + enMonthDays(r.Days)
+			if len(r.Months) == 0 {
+				return text + " of every month"
+			}
+			return text + " of " + joinWords(monthNames(r.Months, enMonths[:]), "and")
+		}
+		text := ruMonthDays(r.Days)
+		if len(r.Months) == 0 {
+			return text + " каждого месяца"
+		}
+		return text + " " + joinWords(monthNames(r.Months, ruMonthsGen[:]), "и")
+	case RRule:
+		return describeRRule(r, en)
+	case WorkdayRule:
+		text := Describe(r.Rule, lang)
+		switch {
+		case en && r.Direction < 0:
+			return text + ", moved to the previous working day"
+		case en:
+			return text + ", moved to the next working day"
+		case r.Direction < 0:
+			return text + ", с переносом на предыдущий рабочий день"
+		}
+		return text + ", с переносом на следующий рабочий день"
+	}
+	return rule.String()
+}
+
+func describeRRule(r RRule, en bool) string {
+	var parts []string
+	units := map[string][2]string{"DAILY": {"day", "days"}, "WEEKLY": {"week", "weeks"}, "MONTHLY": {"month", "months"}, "YEARLY": {"year", "years"}}
+	ruUnits := map[string][3]string{"DAILY": {"день", "дня", "дней"}, "WEEKLY": {"неделю", "недели", "недель"}, "MONTHLY": {"месяц", "месяца", "месяцев"}, "YEARLY": {"год", "года", "лет"}}
+	if en {
+		parts = append(parts, every(r.Interval, units[r.Freq][0], units[r.Freq][1]))
+	} else {
+		gender := 0
+		if r.Freq == "WEEKLY" {
+			gender = 1
+		}
+		parts = append(parts, ruEvery(r.Interval, ruUnits[r.Freq], gender))
+	}
+
+	if len(r.ByMonth) > 0 {
+		if en {
+			parts = append(parts, "in "+joinWords(monthNames(r.ByMonth, enMonths[:]), "and"))
+		} else {
+			parts = append(parts, "в "+joinWords(monthNames(r.ByMonth, ruMonthsPrep[:]), "и"))
+		}
+	}
+	if len(r.ByMonthDay) > 0 {
+		if en {
+			parts = append(parts, "on "+enMonthDays(r.ByMonthDay))
+		} else {
+			parts = append(parts, ruMonthDays(r.ByMonthDay))
+		}
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = describeWeekdayNum(day, en)
+		}
+		if en {
+			parts = append(parts, "on "+joinWords(days, "and"))
+		} else if r.ByDay[0].N == 0 {
+			parts = append(parts, "по "+joinWords(days, "и"))
+		} else {
+			parts = append(parts, prepositionV(days[0])+" "+joinWords(days, "и"))
+		}
+	}
+	if len(r.BySetPos) > 0 {
+		positions := make([]string, len(r.BySetPos))
+		for i, pos := range r.BySetPos {
+			if en {
+				positions[i] = enPosition(pos)
+			} else {
+				positions[i] = ruPosition(pos, 0)
+			}
+		}
+		if en {
+			parts = append(parts, "(only the "+joinWords(positions, "and")+" matching day)")
+		} else {
+			parts = append(parts, "(только "+joinWords(positions, "и")+" подходящий день)")
+		}
+	}
+
+	text := strings.Join(parts, " ")
+	if r.Count > 0 {
+		if en && r.Count == 1 {
+			text += ", once"
+		} else if en {
+			text += ", " + strconv.Itoa(r.Count) + " times"
+		} else {
+			text += ", " + strconv.Itoa(r.Count) + " " + ruPlural(r.Count, [3]string{"раз", "раза", "раз"})
+		}
+	}
+	if !r.Until.IsZero() {
+		if en {
+			text += ", until " + r.Until.Format(describeDateFormat)
+		} else {
+			text += ", до " + r.Until.Format(describeDateFormat)
+		}
+	}
+	return text
+}
+
+func describeWeekdayNum(day WeekdayNum, en bool) string {
+	if en {
+		if day.N == 0 {
+			return enWeekdays[day.Day]
+		}
+		return "the " + enPosition(day.N) + " " + enWeekdays[day.Day]
+	}
+	if day.N == 0 {
+		return ruWeekdaysPl[day.Day]
+	}
+	return ruPosition(day.N, ruWeekdayGen[day.Day]) + " " + ruWeekdays[day.Day]
+}
+
+func every(n int, one, many string) string { // every day, every 3 days
+	if n == 1 {
+		return "every " + one
+	}
+	return "every " + strconv.Itoa(n) + " " + many
+}
+
+func ruEvery(n int, forms [3]string, gender int) string { // каждый день, каждые 3 дня, каждую неделю
+	every := [...]string{"каждый", "каждую", "каждое"}[gender]
+	if n == 1 {
+		return every + " " + forms[0]
+	}
+	if n%10 == 1 && n%100 != 11 {
+		return every + " " + strconv.Itoa(n) + " " + forms[0]
+	}
+	return "каждые " + strconv.Itoa(n) + " " + ruPlural(n, forms)
+}
+
+func ruPlural(n int, forms [3]string) string { // 1 день, 2 дня, 5 дней
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return forms[0]
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		return forms[1]
+	}
+	return forms[2]
+}
+
+func enOrdinal(n int) string { // 1st, 2nd, 3rd, 11th
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+func enPosition(n int) string { // second, last, 3rd to last
+	switch {
+	case n > 0 && n < len(enOrdinals):
+		return enOrdinals[n]
+	case n > 0:
+		return enOrdinal(n)
+	case n == -1:
+		return "last"
+	case n == -2:
+		return "second-to-last"
+	}
+	return enOrdinal(-n) + " to last"
+}
+
+func ruPosition(n int, gender int) string { // второй, последнюю, 3-й с конца
+	suffix := [...]string{"-й", "-ю", "-е"}[gender]
+	switch {
+	case n > 0 && n < len(ruOrdinals):
+		return ruOrdinals[n][gender]
+	case n > 0:
+		return strconv.Itoa(n) + suffix
+	case n >= -2:
+		return ruLastOrdinal[-n-1][gender]
+	}
+	return strconv.Itoa(-n) + suffix + " с конца"
+}
+
+func enMonthDays(days []int) string { // the 15th and the last day
+	var list []string
+	for _, day := range days {
+		if day > 0 {
+			list = append(list, "the "+enOrdinal(day))
+		} else {
+			list = append(list, "the "+enPosition(day)+" day")
+		}
+	}
+	return joinWords(list, "and")
+}
+
+func ruMonthDays(days []int) string { // 1-го и 15-го числа и в последний день
+	var numbers, last []string
+	for _, day := range days {
+		if day > 0 {
+			numbers = append(numbers, strconv.Itoa(day)+"-го")
+		} else {
+			last = append(last, ruPosition(day, 0))
+		}
+	}
+	var list []string
+	if len(numbers) > 0 {
+		list = append(list, joinWords(numbers, "и")+" числа")
+	}
+	if len(last) > 0 {
+		list = append(list, "в "+joinWords(last, "и")+" день")
+	}
+	return strings.Join(list, " и ")
+}
+
+func monthNames(months []int, names []string) []string {
+	list := make([]string, len(months))
+	for i, month := range months {
+		list[i] = names[month]
+	}
+	return list
+}
+
+func joinWords(words []string, conj string) string { // a, b and c
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " " + conj + " " + words[len(words)-1]
+}
+
+func prepositionV(word string) string { // во второй вторник, в последнюю пятницу
+	if strings.HasPrefix(word, "вт") {
+		return "во"
+	}
+	return "в"
+}
+
+func DescribeRepeat(repeat string, lang string) (string, error) { // описание правила, заданного строкой
+	if repeat == "" {
+		return "", nil
+	}
+	rule, err := ParseRule(repeat)
+	if err != nil {
+		return "", err
+	}
+	return Describe(rule, lang), nil
+}
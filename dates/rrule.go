@@ -0,0 +1,358 @@
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRule - правило повторения в формате RFC 5545, например
+// "RRULE:FREQ=MONTHLY;BYDAY=2TU" (второй вторник каждого месяца).
+// Поддерживаются только правила с точностью до дня: FREQ=DAILY, WEEKLY, MONTHLY, YEARLY.
+// Начальной датой серии (DTSTART) считается дата задачи, COUNT считает повторения начиная с неё.
+type RRule struct {
+	Freq       string
+	Interval   int
+	ByMonth    []int
+	ByMonthDay []int
+	ByDay      []WeekdayNum
+	BySetPos   []int
+	WeekStart  time.Weekday
+	Count      int
+	Until      time.Time
+}
+
+type WeekdayNum struct { // элемент BYDAY, например 2TU или -1FR
+	N   int // порядковый номер в месяце или году, 0 - каждый такой день
+	Day time.Weekday
+}
+
+const rrulePrefix = "RRULE:"
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func isRRule(repeat string) bool { // правило в формате RFC 5545 начинается с RRULE: или FREQ=
+	upper := strings.ToUpper(repeat)
+	return strings.HasPrefix(upper, rrulePrefix) || strings.HasPrefix(upper, "FREQ=")
+}
+
+func ParseRRule(repeat string) (RRule, error) {
+	rule := RRule{Interval: 1, WeekStart: time.Monday}
+	value := strings.TrimSpace(repeat)
+	if strings.HasPrefix(strings.ToUpper(value), rrulePrefix) {
+		value = value[len(rrulePrefix):]
+	}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || key == "" || val == "" {
+			return RRule{}, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		if seen[key] {
+			return RRule{}, fmt.Errorf("duplicate RRULE part %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = val
+			default:
+				return RRule{}, fmt.Errorf("unsupported FREQ %s", val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+			if err != nil || rule.Interval < 1 {
+				return RRule{}, fmt.Errorf("invalid INTERVAL %s", val)
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+			if err != nil || rule.Count < 1 {
+				return RRule{}, fmt.Errorf("invalid COUNT %s", val)
+			}
+		case "UNTIL":
+			rule.Until, err = parseRRuleDate(val)
+			if err != nil {
+				return RRule{}, fmt.Errorf("invalid UNTIL %s", val)
+			}
+		case "BYMONTH":
+			rule.ByMonth, err = parseRRuleList(val, 1, 12, false)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseRRuleList(val, 1, 31, true)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseRRuleList(val, 1, 366, true)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(val)
+		case "WKST":
+			day, ok := weekdayCodes[val]
+			if !ok {
+				return RRule{}, fmt.Errorf("invalid WKST %s", val)
+			}
+			rule.WeekStart = day
+		default:
+			return RRule{}, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+		if err != nil {
+			return RRule{}, fmt.Errorf("invalid %s: %v", key, err)
+		}
+	}
+	if err := rule.validate(); err != nil {
+		return RRule{}, err
+	}
+	return rule, nil
+}
+
+func (r RRule) validate() error { // проверка сочетаний частей правила по RFC 5545
+	if r.Freq == "" {
+		return errors.New("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL must not be used together")
+	}
+	if r.Freq == "WEEKLY" && len(r.ByMonthDay) > 0 {
+		return errors.New("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != "MONTHLY" && r.Freq != "YEARLY" {
+			return errors.New("numeric BYDAY is allowed only with FREQ=MONTHLY or FREQ=YEARLY")
+		}
+		if day.N != 0 && r.Freq == "MONTHLY" && (day.N > 5 || day.N < -5) {
+			return fmt.Errorf("invalid BYDAY %d%s for FREQ=MONTHLY", day.N, weekdayNames[day.Day])
+		}
+	}
+	if !r.possible() {
+		return errors.New("BYMONTHDAY never occurs in BYMONTH")
+	}
+	if len(r.BySetPos) > 0 && len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		return errors.New("BYSETPOS requires another BYxxx part")
+	}
+	return nil
+}
+
+func (r RRule) possible() bool { // есть ли в BYMONTH хотя бы один из дней BYMONTHDAY, например BYMONTH=2;BYMONTHDAY=30 недостижимо
+	return len(r.ByMonthDay) == 0 || MonthlyRule{Days: r.ByMonthDay, Months: r.ByMonth}.possible()
+}
+
+func parseRRuleDate(value string) (time.Time, error) { // UNTIL в виде даты или даты-времени, учитывается только дата
+	if len(value) >= 8 {
+		return time.Parse(DefaultDateFormat, value[:8])
+	}
+	return time.Time{}, errors.New("too short")
+}
+
+func parseRRuleList(value string, min, max int, negative bool) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(part)
+		abs := v
+		if abs < 0 && negative {
+			abs = -abs
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("%q", part)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, part := range strings.Split(value, ",") {
+		if len(part) < 2 {
+			return nil, fmt.Errorf("%q", part)
+		}
+		day, ok := weekdayCodes[part[len(part)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("%q", part)
+		}
+		n := 0
+		if ordinal := part[:len(part)-2]; ordinal != "" {
+			var err error
+			n, err = strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n > 53 || n < -53 {
+				return nil, fmt.Errorf("%q", part)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Day: day})
+	}
+	return days, nil
+}
+
+func (r RRule) String() string { // канонический вид с фиксированным порядком частей
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinList(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinList(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = weekdayNames[day.Day]
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinList(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format(DefaultDateFormat))
+	}
+	return rrulePrefix + strings.Join(parts, ";")
+}
+
+func (r RRule) Next(after time.Time) time.Time {
+	after = time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC)
+	rule := r.withDefaults(after)
+	if !rule.possible() { // день из начальной даты может не встречаться в BYMONTH, например 31 января и BYMONTH=2
+		return time.Time{}
+	}
+	start := rule.periodStart(after)
+	for i := 0; i < maxSearchDays; i++ {
+		if !rule.Until.IsZero() && start.After(rule.Until) {
+			return time.Time{}
+		}
+		for _, date := range rule.expand(start) {
+			if date.After(after) {
+				if !rule.Until.IsZero() && date.After(rule.Until) {
+					return time.Time{}
+				}
+				return date
+			}
+		}
+		start = rule.advance(start)
+	}
+	return time.Time{}
+}
+
+func (r RRule) withDefaults(anchor time.Time) RRule { // недостающие части берутся из начальной даты, как в RFC 5545
+	switch r.Freq {
+	case "WEEKLY":
+		if len(r.ByDay) == 0 {
+			r.ByDay = []WeekdayNum{{Day: anchor.Weekday()}}
+		}
+	case "MONTHLY":
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			r.ByMonthDay = []int{anchor.Day()}
+		}
+	case "YEARLY":
+		if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			r.ByMonth = []int{int(anchor.Month())}
+		}
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			r.ByMonthDay = []int{anchor.Day()}
+		}
+	}
+	return r
+}
+
+func (r RRule) periodStart(date time.Time) time.Time { // начало периода (день, неделя, месяц, год), в который попадает дата
+	switch r.Freq {
+	case "WEEKLY":
+		return date.AddDate(0, 0, -((int(date.Weekday()) - int(r.WeekStart) + 7) % 7))
+	case "MONTHLY":
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "YEARLY":
+		return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+func (r RRule) advance(start time.Time) time.Time { // переход к началу периода через INTERVAL периодов
+	switch r.Freq {
+	case "WEEKLY":
+		return start.AddDate(0, 0, 7*r.Interval)
+	case "MONTHLY":
+		return start.AddDate(0, r.Interval, 0)
+	case "YEARLY":
+		return start.AddDate(r.Interval, 0, 0)
+	}
+	return start.AddDate(0, 0, r.Interval)
+}
+
+func (r RRule) expand(start time.Time) []time.Time { // все даты периода, подходящие под правило, с учетом BYSETPOS
+	end := RRule{Freq: r.Freq, Interval: 1}.advance(start)
+	var matched []time.Time
+	for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+		if r.matches(date) {
+			matched = append(matched, date)
+		}
+	}
+	if len(r.BySetPos) == 0 {
+		return matched
+	}
+	var selected []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(matched) + pos
+		}
+		if i >= 0 && i < len(matched) {
+			selected = append(selected, matched[i])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+func (r RRule) matches(date time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(date.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		lastDay := daysInMonth(date.Year(), date.Month())
+		if !containsInt(r.ByMonthDay, date.Day()) && !containsInt(r.ByMonthDay, date.Day()-lastDay-1) {
+			return false
+		}
+	}
+	if len(r.ByDay) > 0 {
+		found := false
+		for _, day := range r.ByDay {
+			if day.Day == date.Weekday() && (day.N == 0 || r.ordinalMatches(date, day.N)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r RRule) ordinalMatches(date time.Time, n int) bool { // номер дня недели в месяце, а для YEARLY без BYMONTH - в году
+	day, total := date.Day(), daysInMonth(date.Year(), date.Month())
+	if r.Freq == "YEARLY" && len(r.ByMonth) == 0 {
+		day, total = date.YearDay(), time.Date(date.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	}
+	if n > 0 {
+		return (day-1)/7+1 == n
+	}
+	return -((total-day)/7 + 1) == n
+}
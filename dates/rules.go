@@ -0,0 +1,242 @@
+package dates
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule - разобранное правило повторения задачи.
+// Next возвращает ближайшую дату повторения строго после after
+// (after - предыдущая дата выполнения) или нулевое время, если такой даты нет.
+// String возвращает правило в каноническом виде, в котором оно хранится в бд.
+type Rule interface {
+	Next(after time.Time) time.Time
+	String() string
+}
+
+type DailyRule struct { // d <число дней>
+	Days int
+}
+
+type YearlyRule struct{} // y
+
+type WeeklyRule struct { // w <дни недели>
+	Days []int // 1 - понедельник, 7 - воскресенье
+}
+
+type MonthlyRule struct { // m <дни месяца> [<месяцы>]
+	Days   []int // 1..31, -1 последний день месяца, -2 предпоследний
+	Months []int // 1..12, пустой список - любой месяц
+}
+
+const (
+	maxDailyDays  = 400
+	maxSearchDays = 366 * 8 // максимальное число дней для поиска подходящей даты
+)
+
+func ParseRule(repeat string) (Rule, error) { // единый разбор правила повторения
+	if fields := strings.Fields(repeat); len(fields) > 1 { // модификатор рабочих дней в конце правила
+		direction := 0
+		switch fields[len(fields)-1] {
+		case workdayForward, workdayAlias:
+			direction = 1
+		case workdayBackward:
+			direction = -1
+		}
+		if direction != 0 {
+			rule, err := ParseRule(strings.Join(fields[:len(fields)-1], " "))
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := rule.(WorkdayRule); ok {
+				return nil, errors.New("duplicate workday modifier")
+			}
+			return WorkdayRule{Rule: rule, Direction: direction, Calendar: WorkCalendar}, nil
+		}
+	}
+	if isRRule(repeat) { // правило в формате RFC 5545
+		rule, err := ParseRRule(repeat)
+		if err != nil {
+			return nil, err
+		}
+		return rule, nil
+	}
+	parts := strings.Fields(repeat)
+	if len(parts) == 0 {
+		return nil, errors.New("repeat is required")
+	}
+	switch parts[0] {
+	case "d": // кол-во дней
+		if len(parts) != 2 {
+			return nil, errors.New("invalid repeat rule format for d")
+		}
+		days, err := strconv.Atoi(parts[1]) // конвертация строки в число дней
+		if err != nil || days < 1 || days > maxDailyDays {
+			return nil, errors.New("invalid number of days")
+		}
+		return DailyRule{Days: days}, nil
+	case "y": // ежегодно
+		if len(parts) != 1 {
+			return nil, errors.New("invalid repeat rule format for y")
+		}
+		return YearlyRule{}, nil
+	case "w": // по дням недели
+		if len(parts) != 2 {
+			return nil, errors.New("invalid repeat rule format for w")
+		}
+		days, err := parseList(parts[1], func(day int) bool { return day >= 1 && day <= 7 })
+		if err != nil {
+			return nil, fmt.Errorf("invalid day of week: %v", err)
+		}
+		return WeeklyRule{Days: days}, nil
+	case "m": // по дням месяца
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, errors.New("invalid repeat rule format for m")
+		}
+		days, err := parseList(parts[1], func(day int) bool { return day >= -2 && day != 0 && day <= 31 })
+		if err != nil {
+			return nil, fmt.Errorf("invalid day of month: %v", err)
+		}
+		var months []int
+		if len(parts) == 3 {
+			months, err = parseList(parts[2], func(month int) bool { return month >= 1 && month <= 12 })
+			if err != nil {
+				return nil, fmt.Errorf("invalid month: %v", err)
+			}
+		}
+		rule := MonthlyRule{Days: days, Months: months}
+		if !rule.possible() {
+			return nil, errors.New("the days of month never occur in the given months")
+		}
+		return rule, nil
+	default:
+		return nil, errors.New("unsupported repeat rule")
+	}
+}
+
+func parseList(s string, valid func(int) bool) ([]int, error) { // разбор списка чисел через запятую
+	seen := make(map[int]bool)
+	var values []int
+	for _, part := range strings.Split(s, ",") {
+		value, err := strconv.Atoi(part)
+		if err != nil || !valid(value) {
+			return nil, fmt.Errorf("%q", part)
+		}
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { // положительные по возрастанию, затем -1, -2
+		if (values[i] < 0) != (values[j] < 0) {
+			return values[i] > 0
+		}
+		if values[i] < 0 {
+			return values[i] > values[j]
+		}
+		return values[i] < values[j]
+	})
+	return values, nil
+}
+
+func joinList(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r DailyRule) Next(after time.Time) time.Time {
+	return after.AddDate(0, 0, r.Days)
+}
+
+func (r DailyRule) String() string {
+	return "d " + strconv.Itoa(r.Days)
+}
+
+func (r YearlyRule) Next(after time.Time) time.Time {
+	return after.AddDate(1, 0, 0) // 29 февраля в невисокосный год переходит на 1 марта
+}
+
+func (r YearlyRule) String() string {
+	return "y"
+}
+
+func (r WeeklyRule) Next(after time.Time) time.Time {
+	for i := 1; i <= 7; i++ {
+		date := after.AddDate(0, 0, i)
+		for _, day := range r.Days {
+			if time.Weekday(day%7) == date.Weekday() { // в time.Weekday воскресенье равно 0
+				return date
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func (r WeeklyRule) String() string {
+	return "w " + joinList(r.Days)
+}
+
+func (r MonthlyRule) Next(after time.Time) time.Time {
+	date := after
+	for i := 0; i < maxSearchDays; i++ {
+		date = date.AddDate(0, 0, 1)
+		if r.matches(date) {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+func (r MonthlyRule) String() string {
+	if len(r.Months) == 0 {
+		return "m " + joinList(r.Days)
+	}
+	return "m " + joinList(r.Days) + " " + joinList(r.Months)
+}
+
+func (r MonthlyRule) matches(date time.Time) bool {
+	if len(r.Months) > 0 && !containsInt(r.Months, int(date.Month())) {
+		return false
+	}
+	lastDay := daysInMonth(date.Year(), date.Month())
+	for _, day := range r.Days {
+		if day == date.Day() || day == date.Day()-lastDay-1 { // для последних дней сравниваем отрицательный номер
+			return true
+		}
+	}
+	return false
+}
+
+func (r MonthlyRule) possible() bool { // есть ли хотя бы одна достижимая дата, например "m 31 2" недостижимо
+	if len(r.Months) == 0 {
+		return true
+	}
+	for _, day := range r.Days {
+		for _, month := range r.Months {
+			if max(day, -day) <= daysInMonth(2024, time.Month(month)) { // 2024 - високосный, 29 февраля достижимо; отрицательные дни считаются с конца месяца
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func daysInMonth(year int, month time.Month) int { // количество дней в месяце
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rust2014/go_final_project/auth"
+	"github.com/rust2014/go_final_project/services"
+)
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+func HandlerSignIn(userService *services.UserService) http.HandlerFunc { // обработчик POST-запроса /api/signin
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request credentials
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+
+		if request.Login != "" { // вход зарегистрированного пользователя
+			if !auth.MultiUser() {
+				http.Error(w, `{"error": "Multi-user mode is disabled"}`, http.StatusBadRequest)
+				return
+			}
+			user, err := userService.Authenticate(request.Login, request.Password)
+			if errors.Is(err, services.ErrWrongCredentials) {
+				http.Error(w, `{"error": "Wrong login or password"}`, http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+				return
+			}
+			writeToken(w, user.ID, user.PasswordHash)
+			return
+		}
+
+		password := auth.Password() // вход по паролю TODO_PASSWORD
+		if password == "" {
+			http.Error(w, `{"error": "Authentication is disabled"}`, http.StatusBadRequest)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(request.Password), []byte(password)) != 1 {
+			http.Error(w, `{"error": "Wrong password"}`, http.StatusUnauthorized)
+			return
+		}
+		writeToken(w, auth.AdminID, password)
+	}
+}
+
+func HandlerSignUp(userService *services.UserService) http.HandlerFunc { // обработчик POST-запроса /api/signup
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.MultiUser() {
+			http.Error(w, `{"error": "Multi-user mode is disabled"}`, http.StatusBadRequest)
+			return
+		}
+		var request credentials
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		user, err := userService.Register(request.Login, request.Password)
+		if errors.Is(err, services.ErrUserExists) {
+			http.Error(w, `{"error": "User already exists"}`, http.StatusConflict)
+			return
+		} else if errors.Is(err, services.ErrInvalidLogin) || errors.Is(err, services.ErrInvalidPassword) {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		} else if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeToken(w, user.ID, user.PasswordHash)
+	}
+}
+
+func writeToken(w http.ResponseWriter, userID int64, secret string) {
+	token, err := auth.NewToken(userID, secret)
+	if err != nil {
+		http.Error(w, `{"error": "Token creation error"}`, http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"token": token})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/auth"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+)
+
+func checklistTask(w http.ResponseWriter, r *http.Request, taskService *services.TaskService, taskID string) (int, bool) { // задача чек-листа, которую пользователь может менять
+	if taskID == "" {
+		http.Error(w, `{"error": "No identifier specified"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(taskID)
+	if err != nil {
+		http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, checkTaskRole(w, taskService, auth.UserID(r.Context()), id, models.RoleEditor)
+}
+
+func writeChecklistError(w http.ResponseWriter, err error) { // ошибки методов чек-листа TaskService
+	switch {
+	case errors.Is(err, repository.ErrItemNotFound):
+		http.Error(w, `{"error": "Checklist item not found"}`, http.StatusNotFound)
+	case errors.Is(err, sql.ErrNoRows) || err.Error() == "task not found":
+		http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+	default:
+		writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	}
+}
+
+func HandlerAddChecklistItem(taskService *services.TaskService) http.HandlerFunc { // обработчик POST-запроса /api/task/checklist
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			TaskID string `json:"task_id"`
+			Text   string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		taskID, ok := checklistTask(w, r, taskService, request.TaskID)
+		if !ok {
+			return
+		}
+		id, err := taskService.AddChecklistItem(auth.UserID(r.Context()), taskID, request.Text)
+		if err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"id": id})
+	}
+}
+
+func HandlerPutChecklistItem(taskService *services.TaskService) http.HandlerFunc { // обработчик PUT-запроса /api/task/checklist, текст и отметка пункта
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			TaskID string  `json:"task_id"`
+			ID     int64   `json:"id"`
+			Text   *string `json:"text"` // без поля - текст не меняется
+			Done   *bool   `json:"done"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		taskID, ok := checklistTask(w, r, taskService, request.TaskID)
+		if !ok {
+			return
+		}
+		if err := taskService.UpdateChecklistItem(auth.UserID(r.Context()), taskID, request.ID, request.Text, request.Done); err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func HandlerReorderChecklist(taskService *services.TaskService) http.HandlerFunc { // обработчик PUT-запроса /api/task/checklist/order
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			TaskID string  `json:"task_id"`
+			IDs    []int64 `json:"ids"` // все пункты в новом порядке
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		taskID, ok := checklistTask(w, r, taskService, request.TaskID)
+		if !ok {
+			return
+		}
+		if err := taskService.ReorderChecklist(auth.UserID(r.Context()), taskID, request.IDs); err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func HandlerDeleteChecklistItem(taskService *services.TaskService) http.HandlerFunc { // обработчик DELETE-запроса /api/task/checklist?task_id=&id=
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskID, ok := checklistTask(w, r, taskService, r.URL.Query().Get("task_id"))
+		if !ok {
+			return
+		}
+		id, ok := queryInt64(w, r, "id")
+		if !ok {
+			return
+		}
+		if err := taskService.DeleteChecklistItem(auth.UserID(r.Context()), taskID, id); err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
@@ -3,13 +3,18 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/rust2014/go_final_project/auth"
 	"github.com/rust2014/go_final_project/dates"
 	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
 	"github.com/rust2014/go_final_project/services"
 	"github.com/rust2014/go_final_project/validation"
 )
@@ -32,6 +37,109 @@ func NextDateHandler(w http.ResponseWriter, r *http.Request) { // GET-обраб
 	fmt.Fprintf(w, nextDate)
 }
 
+func OccurrencesHandler(taskService *services.TaskService) http.HandlerFunc { // GET-обработчик api/occurrences, предпросмотр ближайших дат
+	return func(w http.ResponseWriter, r *http.Request) {
+		date := r.FormValue("date")
+		repeat := r.FormValue("repeat")
+		until := r.FormValue("until")
+
+		timeNow := taskService.Now()
+		if now := r.FormValue("now"); now != "" {
+			var err error
+			timeNow, err = time.Parse(dates.DefaultDateFormat, now)
+			if err != nil {
+				http.Error(w, `{"error": "Invalid 'now' date format"}`, http.StatusBadRequest)
+				return
+			}
+		}
+
+		count := 0
+		if countStr := r.FormValue("count"); countStr != "" {
+			var err error
+			count, err = strconv.Atoi(countStr)
+			if err != nil || count < 1 {
+				http.Error(w, `{"error": "Invalid count"}`, http.StatusBadRequest)
+				return
+			}
+		} else if until == "" {
+			count = 10 // по умолчанию показываем 10 ближайших дат
+		}
+
+		var excluded []string
+		if exdates := r.FormValue("exdates"); exdates != "" { // исключенные даты через запятую
+			excluded = strings.Split(exdates, ",")
+		}
+
+		occurrences, err := dates.Occurrences(timeNow, date, repeat, count, until, excluded)
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, occurrences)
+	}
+}
+
+func DescribeHandler(w http.ResponseWriter, r *http.Request) { // GET-обработчик api/describe, описание правила повторения
+	repeat := r.FormValue("repeat")
+	if repeat == "" {
+		http.Error(w, `{"error": "No repeat rule specified"}`, http.StatusBadRequest)
+		return
+	}
+	text, err := dates.DescribeRepeat(repeat, requestLang(r))
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"repeat_text": text})
+}
+
+func requestLang(r *http.Request) string { // язык описаний: параметр lang или заголовок Accept-Language, по умолчанию русский
+	lang := r.FormValue("lang")
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	if strings.HasPrefix(strings.ToLower(lang), dates.LangEN) {
+		return dates.LangEN
+	}
+	return dates.LangRU
+}
+
+func describeTask(task *models.Task, lang string) { // заполняет описание правила повторения для ответа
+	text, err := dates.DescribeRepeat(task.Repeat, lang)
+	if err == nil {
+		task.RepeatText = text
+	}
+}
+
+func checkTaskRole(w http.ResponseWriter, taskService *services.TaskService, userID int64, id int, role string) bool { // проверка роли перед изменением задачи
+	current, err := taskService.TaskRole(userID, id)
+	if err != nil {
+		if err.Error() == "task not found" {
+			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+			return false
+		}
+		http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+		return false
+	}
+	if models.RoleRank(current) < models.RoleRank(role) {
+		http.Error(w, `{"error": "Insufficient permissions"}`, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func writeListError(w http.ResponseWriter, err error) bool { // ошибки доступа к общему списку
+	switch {
+	case errors.Is(err, services.ErrListNotFound):
+		http.Error(w, `{"error": "List not found"}`, http.StatusNotFound)
+	case errors.Is(err, services.ErrForbidden):
+		http.Error(w, `{"error": "Insufficient permissions"}`, http.StatusForbidden)
+	default:
+		return false
+	}
+	return true
+}
+
 func HandlerTask(taskService *services.TaskService) http.HandlerFunc { // обработчик для AddTask
 	return func(w http.ResponseWriter, r *http.Request) {
 		var task models.Task
@@ -40,7 +148,10 @@ func HandlerTask(taskService *services.TaskService) http.HandlerFunc { // обр
 			http.Error(w, `{"error": "JSON deserialization error:"}`, http.StatusBadRequest)
 			return
 		}
-		id, err := taskService.AddTask(task)
+		id, err := taskService.AddTask(auth.UserID(r.Context()), task)
+		if writeListError(w, err) {
+			return
+		}
 		if err != nil {
 			http.Error(w, `{"error": "Error when adding a task:"}`, http.StatusBadRequest)
 			return
@@ -51,13 +162,49 @@ func HandlerTask(taskService *services.TaskService) http.HandlerFunc { // обр
 
 func HandlerGetTasks(taskService *services.TaskService) http.HandlerFunc { // обработчик для GET-запроса /api/tasks
 	return func(w http.ResponseWriter, r *http.Request) {
-		search := r.URL.Query().Get("search")
-		tasks, err := taskService.GetTasks(search)
+		params := r.URL.Query()
+		limit := 0
+		if value := params.Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+				http.Error(w, `{"error": "Incorrect limit"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		tasks, nextCursor, err := taskService.GetTasks(auth.UserID(r.Context()), services.TaskQuery{
+			Search:  params.Get("search"),
+			From:    params.Get("from"),
+			To:      params.Get("to"),
+			Period:  params.Get("period"),
+			Sort:    params.Get("sort"),
+			Order:   params.Get("order"),
+			Cursor:  params.Get("cursor"),
+			Limit:   limit,
+			Parent:  params.Get("parent"),
+			Blocked: params.Get("blocked"),
+		})
+		var queryErr *repository.QueryError
+		if errors.As(err, &queryErr) { // ошибка в строке поиска: что и где исправить
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": queryErr.Message, "token": queryErr.Token, "position": queryErr.Position})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidFilter) {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
 			return
 		}
-		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"tasks": tasks})
+		lang := requestLang(r)
+		for i := range tasks {
+			describeTask(&tasks[i], lang)
+		}
+		response := map[string]interface{}{"tasks": tasks}
+		if nextCursor != "" { // есть следующая страница: /api/tasks?cursor=...
+			response["next_cursor"] = nextCursor
+		}
+		writeJSONResponse(w, http.StatusOK, response)
 	}
 }
 
@@ -73,7 +220,7 @@ func HandlerGetTask(taskService *services.TaskService) http.HandlerFunc { // о
 			http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
 			return
 		}
-		task, err := taskService.GetTask(id)
+		task, err := taskService.GetTask(auth.UserID(r.Context()), id)
 		if err == sql.ErrNoRows {
 			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
 			return
@@ -81,6 +228,7 @@ func HandlerGetTask(taskService *services.TaskService) http.HandlerFunc { // о
 			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
 			return
 		}
+		describeTask(task, requestLang(r))
 		writeJSONResponse(w, http.StatusOK, task)
 	}
 }
@@ -88,7 +236,9 @@ func HandlerGetTask(taskService *services.TaskService) http.HandlerFunc { // о
 func HandlerPutTask(taskService *services.TaskService) http.HandlerFunc { //обработчик PUT-запроса /api/task (проверка как в HandlerTask)
 	return func(w http.ResponseWriter, r *http.Request) {
 		var task models.Task
-		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		var fields map[string]json.RawMessage // переданные поля, остальные сохраняют прежние значения
+		body, err := io.ReadAll(r.Body)
+		if err != nil || json.Unmarshal(body, &task) != nil || json.Unmarshal(body, &fields) != nil {
 			http.Error(w, `{"error": "Incorrect data format"}`, http.StatusBadRequest)
 			return
 		}
@@ -103,7 +253,8 @@ func HandlerPutTask(taskService *services.TaskService) http.HandlerFunc { //об
 			return
 		}
 
-		if _, err := strconv.Atoi(task.ID); err != nil {
+		taskID, err := strconv.Atoi(task.ID)
+		if err != nil {
 			http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
 			return
 		}
@@ -113,12 +264,67 @@ func HandlerPutTask(taskService *services.TaskService) http.HandlerFunc { //об
 			return
 		}
 
-		if err := validation.ValidateRepeatRule(task.Repeat); err != nil {
+		if !checkTaskRole(w, taskService, auth.UserID(r.Context()), taskID, models.RoleEditor) {
+			return
+		}
+		stored, err := taskService.GetTask(auth.UserID(r.Context()), taskID)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		keepStoredFields(&task, stored, fields)
+
+		repeat, err := validation.NormalizeRepeatRule(task.Repeat)
+		if err != nil {
 			http.Error(w, `{"error": "Incorrect repeat format"}`, http.StatusBadRequest)
 			return
 		}
+		task.Repeat = repeat
 
-		err := taskService.UpdateTask(task)
+		if err := validation.ValidateRepeatEnd(task.Date, task.Repeat, task.RepeatUntil, task.RepeatCount); err != nil {
+			http.Error(w, `{"error": "Incorrect repeat end"}`, http.StatusBadRequest)
+			return
+		}
+
+		task.Exdates, err = validation.NormalizeExdates(task.Repeat, task.Exdates)
+		if err != nil {
+			http.Error(w, `{"error": "Incorrect excluded dates"}`, http.StatusBadRequest)
+			return
+		}
+
+		task.RepeatMode, err = validation.NormalizeRepeatMode(task.RepeatMode)
+		if err != nil {
+			http.Error(w, `{"error": "Incorrect repeat mode"}`, http.StatusBadRequest)
+			return
+		}
+
+		if err := validation.ValidatePriority(task.Priority); err != nil {
+			http.Error(w, `{"error": "Incorrect priority"}`, http.StatusBadRequest)
+			return
+		}
+
+		task.Tags, err = validation.NormalizeTags(task.Tags)
+		if err != nil {
+			http.Error(w, `{"error": "Incorrect tags"}`, http.StatusBadRequest)
+			return
+		}
+
+		if err := validation.ValidateTime(task.Time, task.Duration); err != nil {
+			http.Error(w, `{"error": "Incorrect time or duration"}`, http.StatusBadRequest)
+			return
+		}
+
+		err = taskService.UpdateTask(auth.UserID(r.Context()), task)
+		if writeListError(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidParent) || errors.Is(err, services.ErrInvalidDependency) {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			if err.Error() == "task not found" {
 				http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
@@ -132,6 +338,44 @@ func HandlerPutTask(taskService *services.TaskService) http.HandlerFunc { //об
 	}
 }
 
+// keepStoredFields оставляет сохраненные значения полей, которых нет в запросе на изменение задачи:
+// веб-интерфейс отправляет только id, дату, заголовок, комментарий и правило повторения.
+func keepStoredFields(task *models.Task, stored *models.Task, fields map[string]json.RawMessage) {
+	if task.Date == stored.Date && task.Repeat == stored.Repeat { // дата серии до переноса на рабочий день, пока серия не изменилась
+		task.SeriesDate = stored.SeriesDate
+	}
+	if _, ok := fields["repeat_until"]; !ok && task.Repeat != "" { // без правила повторения серия не ограничивается
+		task.RepeatUntil = stored.RepeatUntil
+	}
+	if _, ok := fields["repeat_count"]; !ok && task.Repeat != "" {
+		task.RepeatCount = stored.RepeatCount
+	}
+	if task.RepeatMode == "" { // пустой режим не переключает задачу обратно на расписание
+		task.RepeatMode = stored.RepeatMode
+	}
+	if _, ok := fields["exdates"]; !ok && task.Repeat != "" { // у задачи без повторения исключенных дат нет
+		task.Exdates = stored.Exdates
+	}
+	if task.ParentID == "" { // как и у list_id, подзадачу отделяет от родителя только "0"
+		task.ParentID = stored.ParentID
+	}
+	if _, ok := fields["blocked_by"]; !ok {
+		task.BlockedBy = stored.BlockedBy
+	}
+	if _, ok := fields["priority"]; !ok {
+		task.Priority = stored.Priority
+	}
+	if _, ok := fields["tags"]; !ok {
+		task.Tags = stored.Tags
+	}
+	if _, ok := fields["time"]; !ok {
+		task.Time = stored.Time
+	}
+	if _, ok := fields["duration"]; !ok && task.Time != "" { // без времени начала длительность сбрасывается
+		task.Duration = stored.Duration
+	}
+}
+
 func HandlerDoneTask(taskService *services.TaskService) http.HandlerFunc { // обработчик POST-запроса /api/task/done
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := r.URL.Query().Get("id")
@@ -144,7 +388,7 @@ func HandlerDoneTask(taskService *services.TaskService) http.HandlerFunc { // о
 			http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
 			return
 		}
-		task, err := taskService.GetTask(id)
+		task, err := taskService.GetTask(auth.UserID(r.Context()), id)
 		if err == sql.ErrNoRows {
 			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
 			return
@@ -152,15 +396,125 @@ func HandlerDoneTask(taskService *services.TaskService) http.HandlerFunc { // о
 			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
 			return
 		}
-		nextDate := ""
-		if task.Repeat != "" {
-			nextDate, err = dates.NextDate(time.Now(), task.Date, task.Repeat)
-			if err != nil {
-				http.Error(w, `{"error": "Error calculating the next date"}`, http.StatusInternalServerError)
-				return
+		if !checkTaskRole(w, taskService, auth.UserID(r.Context()), id, models.RoleEditor) {
+			return
+		}
+		note, err := validation.NormalizeNote(doneNote(r))
+		if err != nil {
+			http.Error(w, `{"error": "Incorrect note"}`, http.StatusBadRequest)
+			return
+		}
+		subtasks, err := taskService.OpenSubtasks(auth.UserID(r.Context()), task)
+		if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		if len(subtasks) > 0 && !taskService.CascadeDone { // сначала нужно выполнить подзадачи
+			ids := make([]string, len(subtasks))
+			for i, subtask := range subtasks {
+				ids[i] = subtask.ID
 			}
+			writeJSONResponse(w, http.StatusConflict, map[string]interface{}{"error": services.ErrOpenSubtasks.Error(), "subtasks": ids})
+			return
+		}
+		blockers, err := taskService.OpenBlockers(auth.UserID(r.Context()), task)
+		if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		err = completeTask(taskService, auth.UserID(r.Context()), task, note, taskService.Now())
+		if errors.Is(err, errNextDate) {
+			http.Error(w, `{"error": "Error calculating the next date"}`, http.StatusInternalServerError)
+			return
 		}
-		if err := taskService.DoneTask(id, nextDate); err != nil {
+		if err != nil {
+			http.Error(w, `{"error": "Task update error"}`, http.StatusInternalServerError)
+			return
+		}
+		response := map[string]interface{}{}
+		if len(blockers) > 0 { // задача выполнена, но раньше нее нужно было выполнить blocked_by
+			ids := make([]string, len(blockers))
+			for i, blocker := range blockers {
+				ids[i] = blocker.ID
+			}
+			response["warning"] = "the task was completed before the tasks blocking it"
+			response["blocked_by"] = ids
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
+
+var errNextDate = errors.New("error calculating the next date")
+
+// completeTask выполняет задачу: разовую удаляет, повторяющуюся переносит на следующую дату
+// со сброшенным чек-листом. Открытые подзадачи выполняются раньше родителя, заметка note
+// записывается в историю только у самой задачи.
+func completeTask(taskService *services.TaskService, userID int64, task *models.Task, note string, now time.Time) error {
+	subtasks, err := taskService.OpenSubtasks(userID, task)
+	if err != nil {
+		return err
+	}
+	for i := range subtasks {
+		if err := completeTask(taskService, userID, &subtasks[i], "", now); err != nil {
+			return err
+		}
+	}
+	nextDate, seriesDate, repeat := "", "", ""
+	if task.RepeatCount != 1 { // при repeat_count = 1 выполнено последнее повторение
+		nextDate, seriesDate, repeat, err = nextTaskDate(task, now, true)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errNextDate, err)
+		}
+	}
+	id, _ := strconv.Atoi(task.ID)
+	return taskService.DoneTask(userID, id, nextDate, seriesDate, repeat, note)
+}
+
+func doneNote(r *http.Request) string { // заметка к выполнению: {"note"} в теле JSON или параметр note
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var request struct {
+			Note string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err == nil {
+			return request.Note
+		}
+	}
+	return r.URL.Query().Get("note")
+}
+
+func HandlerSkipTask(taskService *services.TaskService) http.HandlerFunc { // обработчик POST-запроса /api/task/skip
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := r.URL.Query().Get("id")
+		if idStr == "" {
+			http.Error(w, `{"error": "No identifier specified"}`, http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
+			return
+		}
+		task, err := taskService.GetTask(auth.UserID(r.Context()), id)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		if !checkTaskRole(w, taskService, auth.UserID(r.Context()), id, models.RoleEditor) {
+			return
+		}
+		if task.Repeat == "" {
+			http.Error(w, `{"error": "Only a recurring task can be skipped"}`, http.StatusBadRequest)
+			return
+		}
+		nextDate, seriesDate, repeat, err := nextTaskDate(task, taskService.Now(), false)
+		if err != nil {
+			http.Error(w, `{"error": "Error calculating the next date"}`, http.StatusInternalServerError)
+			return
+		}
+		if err := taskService.SkipTask(auth.UserID(r.Context()), id, nextDate, seriesDate, repeat); err != nil {
 			http.Error(w, `{"error": "Task update error"}`, http.StatusInternalServerError)
 			return
 		}
@@ -168,6 +522,31 @@ func HandlerDoneTask(taskService *services.TaskService) http.HandlerFunc { // о
 	}
 }
 
+// nextTaskDate возвращает дату, дату серии до переноса на рабочий день и правило повторения задачи
+// после текущей даты с учетом исключенных дат. При выполнении (completed) задачи в режиме completion
+// следующая дата отсчитывается от текущего дня, пропуск всегда сдвигает задачу от ее даты.
+// Пустая дата означает, что задача разовая или серия повторений закончилась и задачу нужно удалить.
+func nextTaskDate(task *models.Task, now time.Time, completed bool) (string, string, string, error) {
+	if task.Repeat == "" {
+		return "", "", "", nil
+	}
+	from, series := task.Date, task.SeriesDate
+	if completed && task.RepeatMode == models.RepeatModeCompletion {
+		from, series = now.Format(dates.DefaultDateFormat), ""
+	}
+	nextDate, seriesDate, repeat, err := dates.NextSeriesDate(now, from, series, task.Repeat, task.Exdates)
+	if errors.Is(err, dates.ErrNoNextDate) { // правило больше не дает дат
+		return "", "", "", nil
+	}
+	if err != nil {
+		return "", "", "", err
+	}
+	if task.RepeatUntil != "" && nextDate > task.RepeatUntil { // следующая дата после окончания серии
+		return "", "", "", nil
+	}
+	return nextDate, seriesDate, repeat, nil
+}
+
 func HandlerDeleteTask(taskService *services.TaskService) http.HandlerFunc { // обработчик Delete запроса /api/task
 	return func(w http.ResponseWriter, r *http.Request) {
 		idStr := r.URL.Query().Get("id")
@@ -180,7 +559,10 @@ func HandlerDeleteTask(taskService *services.TaskService) http.HandlerFunc { //
 			http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
 			return
 		}
-		err = taskService.DeleteTask(id)
+		if !checkTaskRole(w, taskService, auth.UserID(r.Context()), id, models.RoleEditor) {
+			return
+		}
+		err = taskService.DeleteTask(auth.UserID(r.Context()), id)
 		if err != nil {
 			if err.Error() == "task not found" {
 				http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
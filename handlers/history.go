@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/auth"
+	"github.com/rust2014/go_final_project/services"
+)
+
+func HandlerTaskHistory(taskService *services.TaskService) http.HandlerFunc { // обработчик GET-запроса /api/task/history?id=
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := r.URL.Query().Get("id")
+		if idStr == "" {
+			http.Error(w, `{"error": "No identifier specified"}`, http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
+			return
+		}
+		history, err := taskService.TaskHistory(auth.UserID(r.Context()), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, `{"error": "Task not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"history": history})
+	}
+}
+
+func HandlerHistory(taskService *services.TaskService) http.HandlerFunc { // обработчик GET-запроса /api/history, лента выполнений
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		limit := 0
+		if value := params.Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+				http.Error(w, `{"error": "Incorrect limit"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		history, nextCursor, err := taskService.History(auth.UserID(r.Context()), params.Get("cursor"), limit)
+		if errors.Is(err, services.ErrInvalidFilter) {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		response := map[string]interface{}{"history": history}
+		if nextCursor != "" { // более ранние выполнения: /api/history?cursor=...
+			response["next_cursor"] = nextCursor
+		}
+		writeJSONResponse(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rust2014/go_final_project/dates"
+	"github.com/rust2014/go_final_project/holidays"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/services"
+)
+
+func HandlerGetHolidays(holidayService *services.HolidayService) http.HandlerFunc { // обработчик GET-запроса /api/holidays
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := holidayService.GetHolidays()
+		if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"holidays": list})
+	}
+}
+
+func HandlerAddHoliday(holidayService *services.HolidayService) http.HandlerFunc { // обработчик POST-запроса /api/holidays
+	return func(w http.ResponseWriter, r *http.Request) {
+		var holiday models.Holiday
+		if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		if _, err := time.Parse(dates.DefaultDateFormat, holiday.Date); err != nil {
+			http.Error(w, `{"error": "Incorrect date format"}`, http.StatusBadRequest)
+			return
+		}
+		if err := holidayService.AddHolidays(map[string]string{holiday.Date: holiday.Name}); err != nil {
+			http.Error(w, `{"error": "Error when adding a holiday"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func HandlerImportHolidays(holidayService *services.HolidayService) http.HandlerFunc { // обработчик POST-запроса /api/holidays/import?format=ics|csv
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			list map[string]string
+			err  error
+		)
+		switch r.URL.Query().Get("format") {
+		case "ics":
+			list, err = holidays.ParseICal(r.Body)
+		case "csv", "":
+			list, err = holidays.ParseCSV(r.Body)
+		default:
+			http.Error(w, `{"error": "Unsupported format"}`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if err := holidayService.AddHolidays(list); err != nil {
+			http.Error(w, `{"error": "Error when adding a holiday"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"imported": len(list)})
+	}
+}
+
+func HandlerDeleteHoliday(holidayService *services.HolidayService) http.HandlerFunc { // обработчик DELETE-запроса /api/holidays?date=
+	return func(w http.ResponseWriter, r *http.Request) {
+		date := r.URL.Query().Get("date")
+		if date == "" {
+			http.Error(w, `{"error": "No date specified"}`, http.StatusBadRequest)
+			return
+		}
+		if err := holidayService.DeleteHoliday(date); err != nil {
+			if err.Error() == "holiday not found" {
+				http.Error(w, `{"error": "Holiday not found"}`, http.StatusNotFound)
+				return
+			}
+			http.Error(w, `{"error": "Holiday deletion error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
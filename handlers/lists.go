@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/auth"
+	"github.com/rust2014/go_final_project/services"
+)
+
+func queryInt64(w http.ResponseWriter, r *http.Request, name string) (int64, bool) { // обязательный числовой параметр запроса
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		http.Error(w, `{"error": "No identifier specified"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeListServiceError(w http.ResponseWriter, err error) { // ошибки ListService
+	if writeListError(w, err) {
+		return
+	}
+	if errors.Is(err, services.ErrLastOwner) {
+		http.Error(w, `{"error": "The list must have at least one owner"}`, http.StatusConflict)
+		return
+	}
+	writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+}
+
+func HandlerGetLists(listService *services.ListService) http.HandlerFunc { // обработчик GET-запроса /api/lists
+	return func(w http.ResponseWriter, r *http.Request) {
+		lists, err := listService.GetLists(auth.UserID(r.Context()))
+		if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"lists": lists})
+	}
+}
+
+func HandlerAddList(listService *services.ListService) http.HandlerFunc { // обработчик POST-запроса /api/lists
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		id, err := listService.CreateList(auth.UserID(r.Context()), request.Name)
+		if err != nil {
+			writeListServiceError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"id": id})
+	}
+}
+
+func HandlerPutList(listService *services.ListService) http.HandlerFunc { // обработчик PUT-запроса /api/lists, переименование
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		if err := listService.RenameList(auth.UserID(r.Context()), request.ID, request.Name); err != nil {
+			writeListServiceError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func HandlerDeleteList(listService *services.ListService) http.HandlerFunc { // обработчик DELETE-запроса /api/lists?id=
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := queryInt64(w, r, "id")
+		if !ok {
+			return
+		}
+		if err := listService.DeleteList(auth.UserID(r.Context()), id); err != nil {
+			writeListServiceError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func HandlerGetMembers(listService *services.ListService) http.HandlerFunc { // обработчик GET-запроса /api/lists/members?id=
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := queryInt64(w, r, "id")
+		if !ok {
+			return
+		}
+		members, err := listService.GetMembers(auth.UserID(r.Context()), id)
+		if err != nil {
+			writeListServiceError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"members": members})
+	}
+}
+
+func HandlerShareList(listService *services.ListService) http.HandlerFunc { // обработчик POST-запроса /api/lists/members
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ListID int64  `json:"list_id"`
+			Login  string `json:"login"`
+			Role   string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		if err := listService.ShareList(auth.UserID(r.Context()), request.ListID, request.Login, request.Role); err != nil {
+			writeListServiceError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+func HandlerRemoveMember(listService *services.ListService) http.HandlerFunc { // обработчик DELETE-запроса /api/lists/members?id=&user_id=
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := queryInt64(w, r, "id")
+		if !ok {
+			return
+		}
+		memberID, ok := queryInt64(w, r, "user_id")
+		if !ok {
+			return
+		}
+		if err := listService.RemoveMember(auth.UserID(r.Context()), id, memberID); err != nil {
+			writeListServiceError(w, err)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/auth"
+	"github.com/rust2014/go_final_project/services"
+)
+
+func HandlerGetTokens(tokenService *services.TokenService) http.HandlerFunc { // обработчик GET-запроса /api/tokens
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := tokenService.GetTokens(auth.UserID(r.Context()))
+		if err != nil {
+			http.Error(w, `{"error": "Request execution error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+	}
+}
+
+func HandlerCreateToken(tokenService *services.TokenService) http.HandlerFunc { // обработчик POST-запроса /api/tokens
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Name     string `json:"name"`
+			ReadOnly bool   `json:"read_only"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, `{"error": "JSON deserialization error"}`, http.StatusBadRequest)
+			return
+		}
+		token, err := tokenService.CreateToken(auth.UserID(r.Context()), request.Name, request.ReadOnly)
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, token)
+	}
+}
+
+func HandlerRevokeToken(tokenService *services.TokenService) http.HandlerFunc { // обработчик DELETE-запроса /api/tokens?id=
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := r.URL.Query().Get("id")
+		if idStr == "" {
+			http.Error(w, `{"error": "No identifier specified"}`, http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, `{"error": "Incorrect identifier format"}`, http.StatusBadRequest)
+			return
+		}
+		err = tokenService.RevokeToken(auth.UserID(r.Context()), id)
+		if err != nil {
+			if err.Error() == "token not found" {
+				http.Error(w, `{"error": "Token not found"}`, http.StatusNotFound)
+				return
+			}
+			http.Error(w, `{"error": "Token deletion error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{})
+	}
+}
@@ -0,0 +1,152 @@
+package holidays
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rust2014/go_final_project/dates"
+)
+
+var dateFormats = []string{dates.DefaultDateFormat, "02.01.2006", "2006-01-02"} // форматы дат в файлах праздников
+
+func LoadFile(path string) (map[string]string, error) { // загрузка праздников из iCal (.ics) или CSV файла
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics", ".ical", ".ifb":
+		return ParseICal(file)
+	}
+	return ParseCSV(file)
+}
+
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, format := range dateFormats {
+		if date, err := time.Parse(format, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// ParseCSV читает строки вида "дата,название", название можно не указывать.
+// Первая строка с неверной датой считается заголовком и пропускается.
+func ParseCSV(r io.Reader) (map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	result := make(map[string]string)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		date, err := parseDate(record[0])
+		if err != nil {
+			if line == 1 { // заголовок
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		name := ""
+		if len(record) > 1 {
+			name = strings.TrimSpace(record[1])
+		}
+		result[date.Format(dates.DefaultDateFormat)] = name
+	}
+	return result, nil
+}
+
+// ParseICal читает события VEVENT: каждый день от DTSTART до DTEND (не включая) считается праздником.
+func ParseICal(r io.Reader) (map[string]string, error) {
+	result := make(map[string]string)
+	var (
+		inEvent    bool
+		start, end time.Time
+		summary    string
+	)
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";") // параметры вроде VALUE=DATE не нужны
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART":
+			if inEvent {
+				start, err = parseICalDate(value)
+			}
+		case "DTEND":
+			if inEvent {
+				end, err = parseICalDate(value)
+			}
+		case "SUMMARY":
+			if inEvent {
+				summary = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(value)
+			}
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, errors.New("VEVENT without DTSTART")
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1) // однодневное событие
+			}
+			for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+				result[date.Format(dates.DefaultDateFormat)] = summary
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func parseICalDate(value string) (time.Time, error) { // 20240101 или 20240101T000000Z, учитывается только дата
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return time.Parse(dates.DefaultDateFormat, value[:8])
+}
+
+func unfoldLines(r io.Reader) ([]string, error) { // строки, продолженные с пробела или табуляции, склеиваются
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
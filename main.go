@@ -1,9 +1,18 @@
 package main
 
 import (
+	"flag"
+	"os"
+
+	"github.com/rust2014/go_final_project/database"
 	"github.com/rust2014/go_final_project/server"
 )
 
 func main() {
-	server.Run()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" { // миграции бд без запуска сервера: migrate [-dry-run]
+		os.Exit(database.MigrateCommand(os.Args[2:], os.Stdout))
+	}
+	memory := flag.Bool("memory", false, "демо-режим: данные в памяти, бд на диске не используется")
+	flag.Parse()
+	server.Run(*memory)
 }
@@ -1,9 +1,118 @@
 package models
 
+const (
+	RepeatModeSchedule   = "schedule"   // следующая дата считается от даты задачи
+	RepeatModeCompletion = "completion" // следующая дата считается от дня выполнения
+)
+
+const (
+	MaxPriority = 4  // самый высокий приоритет задачи
+	MaxTags     = 20 // меток у одной задачи
+	MaxTagLen   = 32 // символов в метке
+	MaxDuration = 24 * 60
+
+	MaxChecklistItems = 100  // пунктов в чек-листе одной задачи
+	MaxChecklistText  = 512  // символов в пункте
+	MaxBlockers       = 20   // блокирующих задач у одной задачи
+	MaxNoteLen        = 1000 // символов в заметке к выполнению
+)
+
 type Task struct {
 	ID      string `json:"id"`
 	Date    string `json:"date"` // 20060102
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+
+	SeriesDate string `json:"-"` // 20060102, дата серии повторений до переноса на рабочий день, пусто - совпадает с Date
+
+	RepeatUntil string `json:"repeat_until,omitempty"` // 20060102, последняя дата серии повторений
+	RepeatCount int    `json:"repeat_count,omitempty"` // сколько раз осталось выполнить, 0 - без ограничения
+
+	Exdates []string `json:"exdates,omitempty"` // 20060102, даты, пропускаемые при переносе задачи
+
+	RepeatMode string `json:"repeat_mode"` // schedule - по расписанию, completion - от дня выполнения
+
+	RepeatText string `json:"repeat_text,omitempty"` // описание правила повторения, в бд не хранится
+
+	ListID string `json:"list_id,omitempty"` // общий список задачи, пусто - личная задача
+
+	Priority int      `json:"priority,omitempty"` // от 1 (низкий) до 4 (срочный), 0 - без приоритета
+	Tags     []string `json:"tags,omitempty"`     // метки в нижнем регистре без #, по алфавиту
+	Time     string   `json:"time,omitempty"`     // 15:04, время начала, пусто - весь день
+	Duration int      `json:"duration,omitempty"` // длительность в минутах, только вместе с Time
+
+	ParentID  string          `json:"parent_id,omitempty"` // родительская задача, пусто - задача верхнего уровня
+	Checklist []ChecklistItem `json:"checklist,omitempty"` // пункты по порядку, меняются через /api/task/checklist
+
+	BlockedBy []string `json:"blocked_by,omitempty"` // задачи, которые нужно выполнить раньше, по возрастанию id
+	Blocked   bool     `json:"blocked,omitempty"`    // есть невыполненная блокирующая задача, в бд не хранится
+
+	Snippet string `json:"snippet,omitempty"` // фрагмент с найденными словами в <mark></mark> при поиске, в бд не хранится
+}
+
+type ChecklistItem struct { // пункт чек-листа задачи
+	ID   int64  `json:"id"`
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+type Completion struct { // выполнение задачи в истории
+	ID          int64  `json:"id"`
+	TaskID      string `json:"task_id"`
+	Title       string `json:"title"`        // заголовок на момент выполнения
+	Date        string `json:"date"`         // 20060102, на какую дату была назначена задача
+	CompletedAt string `json:"completed_at"` // RFC 3339
+	Note        string `json:"note,omitempty"`
+	ListID      string `json:"list_id,omitempty"`
+}
+
+type Holiday struct {
+	Date string `json:"date"` // 20060102
+	Name string `json:"name"`
+}
+
+type User struct {
+	ID           int64  `json:"id"`
+	Login        string `json:"login"`
+	PasswordHash string `json:"-"` // bcrypt
+}
+
+type APIToken struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ReadOnly bool   `json:"read_only"`
+	Created  string `json:"created"`             // RFC 3339
+	LastUsed string `json:"last_used,omitempty"` // RFC 3339, пусто - токен еще не использовался
+	Token    string `json:"token,omitempty"`     // сам токен, возвращается только при создании
+}
+
+const (
+	RoleViewer = "viewer" // просмотр задач списка
+	RoleEditor = "editor" // просмотр и изменение задач
+	RoleOwner  = "owner"  // все права, в том числе управление списком и участниками
+)
+
+func RoleRank(role string) int { // роли упорядочены по правам, 0 - нет доступа
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	}
+	return 0
+}
+
+type List struct { // общий список задач
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"` // роль текущего пользователя
+}
+
+type ListMember struct {
+	UserID int64  `json:"user_id"`
+	Login  string `json:"login"`
+	Role   string `json:"role"`
 }
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+)
+
+func doneFlag(done bool) int { // done хранится числом 0/1 в обоих диалектах
+	if done {
+		return 1
+	}
+	return 0
+}
+
+func requireEditable(tx *database.Tx, userID int64, taskID int) error { // задача, которую пользователь может менять
+	var id int64
+	err := tx.QueryRow("SELECT id FROM scheduler WHERE id = ? AND "+editableTasks, taskID, userID, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("task not found")
+	}
+	return err
+}
+
+func (r *sqlRepository) AddChecklistItem(userID int64, taskID int, text string) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := requireEditable(tx, userID, taskID); err != nil {
+		return 0, err
+	}
+	var id int64
+	err = tx.QueryRow("INSERT INTO checklist (task_id, position, text) VALUES (?, (SELECT COALESCE(MAX(position), 0) + 1 FROM checklist WHERE task_id = ?), ?) RETURNING id",
+		taskID, taskID, text).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *sqlRepository) UpdateChecklistItem(userID int64, taskID int, item models.ChecklistItem) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireEditable(tx, userID, taskID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("UPDATE checklist SET text = ?, done = ? WHERE id = ? AND task_id = ?", item.Text, doneFlag(item.Done), item.ID, taskID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepository) ReorderChecklist(userID int64, taskID int, ids []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireEditable(tx, userID, taskID); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		result, err := tx.Exec("UPDATE checklist SET position = ? WHERE id = ? AND task_id = ?", i+1, id, taskID)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if rowsAffected == 0 {
+			return ErrItemNotFound
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepository) DeleteChecklistItem(userID int64, taskID int, id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireEditable(tx, userID, taskID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM checklist WHERE id = ? AND task_id = ?", id, taskID)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrItemNotFound
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepository) loadChecklist(tasks []models.Task) error { // пункты чек-листа для списка задач одним запросом
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]any, len(tasks))
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+		index[task.ID] = i
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	rows, err := r.db.Query("SELECT task_id, id, text, done FROM checklist WHERE task_id IN ("+placeholders+") ORDER BY position, id", ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID string
+		var item models.ChecklistItem
+		var done int
+		if err := rows.Scan(&taskID, &item.ID, &item.Text, &done); err != nil {
+			return err
+		}
+		item.Done = done != 0
+		if i, ok := index[taskID]; ok {
+			tasks[i].Checklist = append(tasks[i].Checklist, item)
+		}
+	}
+	return rows.Err()
+}
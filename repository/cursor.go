@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/models"
+)
+
+const ( // порядок задач в GetTasks
+	SortRelevance = "relevance" // по релевантности поиска, затем по дате
+	SortDate      = "date"
+	SortTitle     = "title"
+	SortCreated   = "created" // по id: идентификаторы выдаются по возрастанию
+	SortPriority  = "priority"
+	SortTime      = "time" // по дате, в пределах дня по времени начала, задачи на весь день первыми
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor - позиция последней полученной задачи: следующая страница начинается после нее.
+// Key - значение колонки сортировки (sortKey), для SortCreated не нужен.
+// Ранг релевантности вычисляется заново при каждом запросе, поэтому для SortRelevance
+// вместо Key хранится Offset - сколько задач уже выдано.
+type Cursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Key    string `json:"k,omitempty"`
+	Offset int    `json:"o,omitempty"`
+	ID     int64  `json:"i"`
+}
+
+func NewCursor(sort string, desc bool, last models.Task) (*Cursor, error) { // курсор после задачи last
+	id, err := strconv.ParseInt(last.ID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &Cursor{Sort: sort, Desc: desc, ID: id}
+	switch sort {
+	case SortDate, SortTitle, SortPriority, SortTime:
+		cursor.Key = sortKey(sort, last)
+	case SortCreated:
+	default:
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func NewRelevanceCursor(desc bool, offset int, last models.Task) (*Cursor, error) { // курсор после offset задач, последняя - last
+	id, err := strconv.ParseInt(last.ID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{Sort: SortRelevance, Desc: desc, Offset: offset, ID: id}, nil
+}
+
+func (c *Cursor) String() string { // непрозрачная строка для next_cursor
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ParseCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+	switch cursor.Sort {
+	case SortDate, SortTitle, SortCreated, SortPriority, SortTime:
+		return &cursor, nil
+	case SortRelevance:
+		if cursor.Offset > 0 {
+			return &cursor, nil
+		}
+	}
+	return nil, ErrInvalidCursor
+}
+
+func (f TaskFilter) normalize() TaskFilter { // порядок из курсора; релевантность только при поиске по словам
+	if f.After != nil {
+		f.Sort, f.Desc = f.After.Sort, f.After.Desc
+	}
+	if f.Sort == SortRelevance && !f.Query.HasText() {
+		f.Sort = SortDate
+	}
+	return f
+}
+
+func sortColumn(sort string) string { // колонка сортировки перед id, пусто - только id
+	switch sort {
+	case SortDate, "":
+		return "date"
+	case SortTitle:
+		return "title"
+	case SortPriority:
+		return "priority"
+	case SortTime:
+		return "date || start_time" // 20240101 раньше 2024010109:00
+	}
+	return ""
+}
+
+func (f TaskFilter) orderSQL() string {
+	direction := ""
+	if f.Desc {
+		direction = " DESC"
+	}
+	switch f.Sort {
+	case SortRelevance:
+		return " ORDER BY COALESCE(found.search_rank, 0)" + direction + ", date, id"
+	case SortCreated:
+		return " ORDER BY id" + direction
+	}
+	return " ORDER BY " + sortColumn(f.Sort) + direction + ", id" + direction
+}
+
+func (f TaskFilter) offset() int { // пропуск задач для курсора по релевантности
+	if f.After == nil {
+		return 0
+	}
+	return f.After.Offset
+}
+
+func (f TaskFilter) keysetSQL() (string, []any) { // задачи после курсора в порядке orderSQL
+	operator := " > "
+	if f.After.Desc {
+		operator = " < "
+	}
+	column := sortColumn(f.After.Sort)
+	if column == "" {
+		return "id" + operator + "?", []any{f.After.ID}
+	}
+	return "(" + column + operator + "? OR (" + column + " = ? AND id" + operator + "?))", []any{f.After.Key, f.After.Key, f.After.ID}
+}
+
+func sortKey(sort string, task models.Task) string { // значение колонки сортировки для хранилища в памяти
+	switch sort {
+	case SortDate, "":
+		return task.Date
+	case SortTitle:
+		return task.Title
+	case SortPriority:
+		return strconv.Itoa(task.Priority) // одна цифра, строки сравниваются как числа
+	case SortTime:
+		return task.Date + task.Time
+	}
+	return ""
+}
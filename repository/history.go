@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/rust2014/go_final_project/models"
+)
+
+func (r *sqlRepository) CompleteTask(userID int64, id int, nextDate string, seriesDate string, repeat string, completion models.Completion) (int64, error) {
+	listID, err := ParseListID(completion.ListID)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if nextDate == "" {
+		err = deleteTask(tx, userID, id)
+	} else {
+		err = moveTask(tx, userID, id, nextDate, seriesDate, repeat, true)
+	}
+	if err != nil {
+		return 0, err
+	}
+	var historyID int64
+	err = tx.QueryRow("INSERT INTO history (task_id, user_id, list_id, title, date, completed_at, note) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id",
+		completion.TaskID, userID, listID, completion.Title, completion.Date, completion.CompletedAt, completion.Note).Scan(&historyID)
+	if err != nil {
+		return 0, err
+	}
+	return historyID, tx.Commit()
+}
+
+func (r *sqlRepository) GetHistory(userID int64, filter HistoryFilter) ([]models.Completion, error) {
+	query := "SELECT id, task_id, title, date, completed_at, note, list_id FROM history WHERE " + visibleTasks
+	args := []any{userID, userID}
+	if filter.TaskID != 0 {
+		query += " AND task_id = ?"
+		args = append(args, filter.TaskID)
+	}
+	if filter.Before != 0 {
+		query += " AND id < ?"
+		args = append(args, filter.Before)
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.Completion{}
+	for rows.Next() {
+		var completion models.Completion
+		if err := rows.Scan(&completion.ID, &completion.TaskID, &completion.Title, &completion.Date, &completion.CompletedAt, &completion.Note, &completion.ListID); err != nil {
+			return nil, err
+		}
+		if completion.ListID == "0" { // личная задача
+			completion.ListID = ""
+		}
+		history = append(history, completion)
+	}
+	return history, rows.Err()
+}
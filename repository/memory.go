@@ -0,0 +1,498 @@
+package repository
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/rust2014/go_final_project/models"
+)
+
+// ListRoles - источник ролей в общих списках для хранилища в памяти
+// (списки и участники остаются в ListService).
+type ListRoles interface {
+	ListRole(userID int64, listID int64) (string, error)
+}
+
+type memoryTask struct {
+	task   models.Task
+	userID int64
+	listID int64
+}
+
+// memoryRepository - TaskRepository без бд для демо-режима (--memory) и тестов.
+// Данные пропадают при остановке сервера.
+type memoryRepository struct {
+	mu     sync.RWMutex
+	tasks  map[int]*memoryTask
+	nextID int
+	lists  ListRoles
+
+	nextItemID int64 // идентификаторы пунктов чек-листа, общие для всех задач
+
+	history       []memoryCompletion // по возрастанию id
+	nextHistoryID int64
+}
+
+type memoryCompletion struct {
+	completion models.Completion
+	userID     int64
+	listID     int64
+}
+
+func NewMemory(lists ListRoles) TaskRepository { // lists == nil - только личные задачи
+	return &memoryRepository{tasks: map[int]*memoryTask{}, lists: lists}
+}
+
+func copyTask(stored *memoryTask) models.Task { // копия, чтобы вызывающий код не менял хранилище
+	task := stored.task
+	task.Exdates = slices.Clone(stored.task.Exdates)
+	task.Tags = slices.Clone(stored.task.Tags)
+	task.Checklist = slices.Clone(stored.task.Checklist)
+	task.BlockedBy = slices.Clone(stored.task.BlockedBy)
+	task.RepeatText, task.Snippet, task.Blocked = "", "", false // в бд эти поля не хранятся
+	if stored.listID != 0 {
+		task.ListID = strconv.FormatInt(stored.listID, 10)
+	}
+	return task
+}
+
+func (r *memoryRepository) role(userID int64, stored *memoryTask) (string, error) { // роль как в TaskRole, пусто - нет доступа
+	if stored.listID == 0 {
+		if stored.userID != userID {
+			return "", nil
+		}
+		return models.RoleOwner, nil
+	}
+	return r.ListRole(userID, stored.listID)
+}
+
+func (r *memoryRepository) editable(userID int64, id int) (*memoryTask, error) { // задача, которую пользователь может менять
+	stored, ok := r.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found")
+	}
+	role, err := r.role(userID, stored)
+	if err != nil {
+		return nil, err
+	}
+	if models.RoleRank(role) < models.RoleRank(models.RoleEditor) {
+		return nil, fmt.Errorf("task not found")
+	}
+	return stored, nil
+}
+
+func (r *memoryRepository) GetTasks(userID int64, filter TaskFilter) ([]models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	filter = filter.normalize()
+	var terms []searchTerm
+	if filter.Query != nil {
+		terms = filter.Query.textTerms()
+	}
+	tasks := []models.Task{}
+	ranks := map[string]int{} // как bm25 в SQLite: совпадение в заголовке весит больше
+	for _, stored := range r.tasks {
+		if (filter.From != "" && stored.task.Date < filter.From) || (filter.To != "" && stored.task.Date > filter.To) {
+			continue
+		}
+		if filter.Parent != 0 && stored.task.ParentID != strconv.FormatInt(filter.Parent, 10) {
+			continue
+		}
+		task := copyTask(stored)
+		task.Blocked = r.blocked(stored)
+		if filter.Query != nil && !filter.Query.match(task) {
+			continue
+		}
+		if len(terms) > 0 {
+			title, titleMatches := highlight(task.Title, FieldTitle, terms)
+			comment, commentMatches := highlight(task.Comment, FieldComment, terms)
+			task.Snippet = snippetHTML(title)
+			if titleMatches == 0 {
+				task.Snippet = snippetHTML(comment)
+			}
+			ranks[task.ID] = 10*titleMatches + commentMatches
+		}
+		role, err := r.role(userID, stored)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	id := func(task models.Task) int {
+		id, _ := strconv.Atoi(task.ID)
+		return id
+	}
+	compare := func(a, b models.Task) int { // порядок как в TaskFilter.orderSQL
+		var c int
+		switch filter.Sort {
+		case SortRelevance:
+			if c = cmp.Compare(ranks[b.ID], ranks[a.ID]); c == 0 {
+				return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(id(a), id(b)))
+			}
+		case SortCreated:
+			c = cmp.Compare(id(a), id(b))
+		default:
+			c = cmp.Or(cmp.Compare(sortKey(filter.Sort, a), sortKey(filter.Sort, b)), cmp.Compare(id(a), id(b)))
+		}
+		if filter.Desc {
+			return -c
+		}
+		return c
+	}
+	if filter.After != nil && filter.After.Sort != SortRelevance { // как keysetSQL: после пары (Key, ID) курсора
+		tasks = slices.DeleteFunc(tasks, func(task models.Task) bool {
+			c := cmp.Or(cmp.Compare(sortKey(filter.Sort, task), filter.After.Key), cmp.Compare(int64(id(task)), filter.After.ID))
+			if filter.Desc {
+				c = -c
+			}
+			return c <= 0
+		})
+	}
+	slices.SortFunc(tasks, compare)
+	tasks = tasks[min(filter.offset(), len(tasks)):]
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, nil
+}
+
+func (r *memoryRepository) GetTask(userID int64, id int) (*models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.tasks[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	role, err := r.role(userID, stored)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, sql.ErrNoRows
+	}
+	task := copyTask(stored)
+	task.Blocked = r.blocked(stored)
+	return &task, nil
+}
+
+func (r *memoryRepository) TaskRole(userID int64, id int) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored, ok := r.tasks[id]
+	if !ok {
+		return "", fmt.Errorf("task not found")
+	}
+	role, err := r.role(userID, stored)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", fmt.Errorf("task not found")
+	}
+	return role, nil
+}
+
+func (r *memoryRepository) ListRole(userID int64, listID int64) (string, error) {
+	if r.lists == nil {
+		return "", nil
+	}
+	return r.lists.ListRole(userID, listID)
+}
+
+func (r *memoryRepository) AddTask(userID int64, task models.Task) (int64, error) {
+	listID, err := ParseListID(task.ListID)
+	if err != nil {
+		return 0, err
+	}
+	parentID, err := ParseParentID(task.ParentID)
+	if err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	task.ID = strconv.Itoa(r.nextID)
+	task.ListID = ""
+	task.ParentID = parentString(parentID)
+	task.Exdates = sortedValues(task.Exdates)
+	task.Tags = sortedValues(task.Tags)
+	task.Checklist = slices.Clone(task.Checklist)
+	for i := range task.Checklist {
+		r.nextItemID++
+		task.Checklist[i].ID = r.nextItemID
+	}
+	r.tasks[r.nextID] = &memoryTask{task: task, userID: userID, listID: listID}
+	return int64(r.nextID), nil
+}
+
+func (r *memoryRepository) UpdateTask(userID int64, task models.Task) error {
+	id, err := strconv.Atoi(task.ID)
+	if err != nil {
+		return fmt.Errorf("task not found")
+	}
+	listID, err := ParseListID(task.ListID)
+	if err != nil {
+		return err
+	}
+	parentID, err := ParseParentID(task.ParentID)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.editable(userID, id)
+	if err != nil {
+		return err
+	}
+	if task.ListID != "" { // перенос в другой список или в личные задачи вместе с подзадачами
+		for _, moved := range append(r.subtasks(task.ID), stored) {
+			moved.listID = listID
+			moved.userID = userID
+		}
+	}
+	task.ListID = ""
+	task.ParentID = parentString(parentID)
+	task.Exdates = sortedValues(task.Exdates)
+	task.Tags = sortedValues(task.Tags)
+	task.Checklist = stored.task.Checklist // чек-лист меняется отдельными методами
+	stored.task = task
+	return nil
+}
+
+func (r *memoryRepository) MoveTask(userID int64, id int, nextDate string, seriesDate string, repeat string, completed bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.editable(userID, id)
+	if err != nil {
+		return err
+	}
+	stored.move(nextDate, seriesDate, repeat, completed)
+	return nil
+}
+
+func (stored *memoryTask) move(nextDate string, seriesDate string, repeat string, completed bool) { // MoveTask без блокировки
+	stored.task.Date = nextDate
+	stored.task.SeriesDate = seriesDate
+	stored.task.Repeat = repeat
+	if completed && stored.task.RepeatCount > 0 {
+		stored.task.RepeatCount--
+	}
+	stored.task.Exdates = slices.DeleteFunc(stored.task.Exdates, func(date string) bool { // прошедшие исключения больше не нужны
+		return date < nextDate
+	})
+	if completed { // у следующего повторения чек-лист снова не отмечен
+		for i := range stored.task.Checklist {
+			stored.task.Checklist[i].Done = false
+		}
+	}
+}
+
+func (r *memoryRepository) DeleteTask(userID int64, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.editable(userID, id); err != nil {
+		return err
+	}
+	r.deleteTask(id)
+	return nil
+}
+
+func (r *memoryRepository) DeleteListTasks(listID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, stored := range r.tasks {
+		if stored.listID == listID {
+			r.deleteTask(id)
+		}
+	}
+	r.history = slices.DeleteFunc(r.history, func(stored memoryCompletion) bool { return stored.listID == listID })
+	return nil
+}
+
+func (r *memoryRepository) deleteTask(id int) { // DeleteTask без блокировки
+	delete(r.tasks, id)
+	for _, stored := range r.tasks { // подзадачи остаются без родителя, заблокированные задачи - без этой блокирующей
+		if stored.task.ParentID == strconv.Itoa(id) {
+			stored.task.ParentID = ""
+		}
+		stored.task.BlockedBy = slices.DeleteFunc(stored.task.BlockedBy, func(blocker string) bool { return blocker == strconv.Itoa(id) })
+	}
+}
+
+func (r *memoryRepository) AddChecklistItem(userID int64, taskID int, text string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.editable(userID, taskID)
+	if err != nil {
+		return 0, err
+	}
+	r.nextItemID++
+	stored.task.Checklist = append(stored.task.Checklist, models.ChecklistItem{ID: r.nextItemID, Text: text})
+	return r.nextItemID, nil
+}
+
+func (r *memoryRepository) UpdateChecklistItem(userID int64, taskID int, item models.ChecklistItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.editable(userID, taskID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(stored.task.Checklist, func(current models.ChecklistItem) bool { return current.ID == item.ID })
+	if i < 0 {
+		return ErrItemNotFound
+	}
+	stored.task.Checklist[i] = item
+	return nil
+}
+
+func (r *memoryRepository) ReorderChecklist(userID int64, taskID int, ids []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.editable(userID, taskID)
+	if err != nil {
+		return err
+	}
+	checklist := make([]models.ChecklistItem, 0, len(stored.task.Checklist))
+	for _, id := range ids {
+		i := slices.IndexFunc(stored.task.Checklist, func(item models.ChecklistItem) bool { return item.ID == id })
+		if i < 0 {
+			return ErrItemNotFound
+		}
+		checklist = append(checklist, stored.task.Checklist[i])
+	}
+	for _, item := range stored.task.Checklist { // пункты, которых нет в ids, остаются в конце, как в бд
+		if !slices.Contains(ids, item.ID) {
+			checklist = append(checklist, item)
+		}
+	}
+	stored.task.Checklist = checklist
+	return nil
+}
+
+func (r *memoryRepository) DeleteChecklistItem(userID int64, taskID int, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.editable(userID, taskID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(stored.task.Checklist, func(item models.ChecklistItem) bool { return item.ID == id })
+	if i < 0 {
+		return ErrItemNotFound
+	}
+	stored.task.Checklist = slices.Delete(stored.task.Checklist, i, i+1)
+	return nil
+}
+
+func (r *memoryRepository) blocked(stored *memoryTask) bool { // как blockedTask в sql.go
+	for _, blocker := range stored.task.BlockedBy {
+		id, _ := strconv.Atoi(blocker)
+		if other, ok := r.tasks[id]; ok && (other.task.Repeat == "" || other.task.Date <= stored.task.Date) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryRepository) subtasks(id string) []*memoryTask { // подзадачи на всех уровнях вложенности
+	var result []*memoryTask
+	for _, stored := range r.tasks {
+		if stored.task.ParentID == id {
+			result = append(result, stored)
+			result = append(result, r.subtasks(stored.task.ID)...)
+		}
+	}
+	return result
+}
+
+func parentString(parentID int64) string { // ParentID как после чтения из бд
+	if parentID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(parentID, 10)
+}
+
+func sortedValues(values []string) []string { // без повторов и по порядку, как в таблицах exdates и tags
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return slices.Compact(values)
+}
+
+func (r *memoryRepository) CompleteTask(userID int64, id int, nextDate string, seriesDate string, repeat string, completion models.Completion) (int64, error) {
+	listID, err := ParseListID(completion.ListID)
+	if err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.editable(userID, id)
+	if err != nil {
+		return 0, err
+	}
+	if nextDate == "" {
+		r.deleteTask(id)
+	} else {
+		stored.move(nextDate, seriesDate, repeat, true)
+	}
+	r.nextHistoryID++
+	completion.ID = r.nextHistoryID
+	completion.ListID = ""
+	r.history = append(r.history, memoryCompletion{completion: completion, userID: userID, listID: listID})
+	return completion.ID, nil
+}
+
+func (r *memoryRepository) GetHistory(userID int64, filter HistoryFilter) ([]models.Completion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := []models.Completion{}
+	for i := len(r.history) - 1; i >= 0; i-- {
+		stored := r.history[i]
+		if (filter.TaskID != 0 && stored.completion.TaskID != strconv.FormatInt(filter.TaskID, 10)) ||
+			(filter.Before != 0 && stored.completion.ID >= filter.Before) {
+			continue
+		}
+		visible := stored.listID == 0 && stored.userID == userID // как visibleTasks
+		if stored.listID != 0 {
+			role, err := r.ListRole(userID, stored.listID)
+			if err != nil {
+				return nil, err
+			}
+			visible = role != ""
+		}
+		if !visible {
+			continue
+		}
+		completion := stored.completion
+		if stored.listID != 0 {
+			completion.ListID = strconv.FormatInt(stored.listID, 10)
+		}
+		history = append(history, completion)
+		if filter.Limit > 0 && len(history) == filter.Limit {
+			break
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rust2014/go_final_project/dates"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/validation"
+)
+
+// Query - разобранная строка поиска /api/tasks. Условия через пробел объединяются по И,
+// группы через OR - по ИЛИ:
+//
+//	купить хлеб OR title:"отчет за квартал" -черновик after:today repeat:yes
+//
+// Слово без поля ищется по префиксу в заголовке и комментарии, фраза в кавычках - целиком.
+// Поля: title, comment, date, before, after (даты 02.01.2006, 20060102, today, tomorrow, yesterday),
+// repeat и blocked (yes или no), tag (метка, # можно не писать) и priority (от 1 до 4). Минус перед условием - отрицание. Дата 02.01.2006 без поля - задачи на эту дату.
+type Query struct {
+	Groups [][]Condition // ИЛИ групп, в группе - И условий
+}
+
+const ( // поля условий
+	FieldText     = ""         // заголовок или комментарий
+	FieldTitle    = "title"    // только заголовок
+	FieldComment  = "comment"  // только комментарий
+	FieldDate     = "date"     // date = Value
+	FieldBefore   = "before"   // date < Value
+	FieldAfter    = "after"    // date > Value
+	FieldRepeat   = "repeat"   // Value "yes" - повторяющиеся задачи, "no" - разовые
+	FieldTag      = "tag"      // у задачи есть метка Value
+	FieldPriority = "priority" // priority = Value
+	FieldBlocked  = "blocked"  // Value "yes" - задачи с невыполненными блокирующими задачами, "no" - остальные
+)
+
+type Condition struct {
+	Field  string
+	Negate bool
+	Value  string     // дата 20060102, yes/no для repeat и blocked, метка или приоритет
+	term   searchTerm // для FieldText, FieldTitle и FieldComment
+}
+
+// QueryError - синтаксическая ошибка в строке поиска. Position - номер символа
+// (с нуля), с которого начинается ошибочный фрагмент Token.
+type QueryError struct {
+	Message  string
+	Token    string
+	Position int
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s: %q at position %d", e.Message, e.Token, e.Position)
+}
+
+type queryToken struct {
+	text     string
+	position int // в символах
+}
+
+func (t queryToken) error(format string, args ...any) *QueryError {
+	return &QueryError{Message: fmt.Sprintf(format, args...), Token: t.text, Position: t.position}
+}
+
+func splitQuery(input string) ([]queryToken, error) { // слова через пробел, пробелы внутри кавычек не разделяют
+	var tokens []queryToken
+	var current strings.Builder
+	start, quoted := -1, false
+	position := 0
+	for _, r := range input {
+		switch {
+		case unicode.IsSpace(r) && !quoted:
+			if start >= 0 {
+				tokens = append(tokens, queryToken{text: current.String(), position: start})
+				current.Reset()
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = position
+			}
+			if r == '"' {
+				quoted = !quoted
+			}
+			current.WriteRune(r)
+		}
+		position++
+	}
+	if quoted {
+		return nil, &QueryError{Message: "unclosed quote", Token: current.String(), Position: start}
+	}
+	if start >= 0 {
+		tokens = append(tokens, queryToken{text: current.String(), position: start})
+	}
+	return tokens, nil
+}
+
+// ParseQuery разбирает строку поиска; относительные даты (today) считаются от now.
+// Пустая строка - nil без ошибки.
+func ParseQuery(input string, now time.Time) (*Query, error) {
+	tokens, err := splitQuery(input)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	query := &Query{}
+	var group []Condition
+	for i, token := range tokens {
+		if token.text == "OR" {
+			if len(group) == 0 || i == len(tokens)-1 {
+				return nil, token.error("OR must be between conditions")
+			}
+			query.Groups = append(query.Groups, group)
+			group = nil
+			continue
+		}
+		condition, err := parseCondition(token, now)
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, condition)
+	}
+	query.Groups = append(query.Groups, group)
+	return query, nil
+}
+
+func parseCondition(token queryToken, now time.Time) (Condition, error) {
+	var condition Condition
+	text := token.text
+	if strings.HasPrefix(text, "-") {
+		condition.Negate = true
+		text = text[1:]
+		if text == "" {
+			return condition, token.error("nothing to exclude")
+		}
+	}
+	if date, err := time.Parse("02.01.2006", text); err == nil { // дата без поля, как раньше в search
+		condition.Field, condition.Value = FieldDate, date.Format(dates.DefaultDateFormat)
+		return condition, nil
+	}
+
+	field, value, hasField := "", text, false
+	if name, rest, ok := strings.Cut(text, ":"); ok && name != "" && strings.IndexFunc(name, func(r rune) bool {
+		return r > unicode.MaxASCII || !unicode.IsLetter(r)
+	}) < 0 { // поле - латинские буквы перед двоеточием, "Встреча:" остается текстом
+		field, value, hasField = strings.ToLower(name), rest, true
+	}
+	if hasField && value == "" {
+		return condition, token.error("empty value for %s", field)
+	}
+	condition.Field = field
+
+	switch field {
+	case FieldText, FieldTitle, FieldComment:
+		phrase := strings.HasPrefix(value, `"`)
+		words := splitWords(strings.Trim(value, `"`))
+		if len(words) == 0 {
+			return condition, token.error("nothing to search for")
+		}
+		condition.term = searchTerm{words: words, prefix: !phrase, column: field}
+	case FieldDate, FieldBefore, FieldAfter:
+		date, err := ParseDate(value, now)
+		if err != nil {
+			return condition, token.error("invalid date %q", value)
+		}
+		condition.Value = date
+	case FieldRepeat, FieldBlocked:
+		value = strings.ToLower(value)
+		if value != "yes" && value != "no" {
+			return condition, token.error("%s must be yes or no", field)
+		}
+		condition.Value = value
+	case FieldTag:
+		tag, err := validation.NormalizeTag(value)
+		if err != nil {
+			return condition, token.error("invalid tag %q", value)
+		}
+		condition.Value = tag
+	case FieldPriority:
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < 1 || priority > models.MaxPriority {
+			return condition, token.error("priority must be from 1 to %d", models.MaxPriority)
+		}
+		condition.Value = value
+	default:
+		return condition, token.error("unknown field %s", field)
+	}
+	return condition, nil
+}
+
+func ParseDate(value string, now time.Time) (string, error) { // дата условия или фильтра в формате 20060102
+	switch strings.ToLower(value) {
+	case "today":
+		return now.Format(dates.DefaultDateFormat), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format(dates.DefaultDateFormat), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format(dates.DefaultDateFormat), nil
+	}
+	for _, layout := range []string{"02.01.2006", dates.DefaultDateFormat} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(dates.DefaultDateFormat), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date")
+}
+
+// And добавляет условие, которое должно выполняться вместе с запросом: в каждую группу ИЛИ.
+// Исходный запрос не меняется, для nil получается запрос из одного условия.
+func (q *Query) And(condition Condition) *Query {
+	if q == nil {
+		return &Query{Groups: [][]Condition{{condition}}}
+	}
+	result := &Query{Groups: make([][]Condition, len(q.Groups))}
+	for i, group := range q.Groups {
+		result.Groups[i] = append(slices.Clip(group), condition)
+	}
+	return result
+}
+
+func (q *Query) HasText() bool { // есть слова для поиска, результаты можно ранжировать
+	return q != nil && len(q.textTerms()) > 0
+}
+
+func (q *Query) textTerms() []searchTerm { // слова без отрицания для ранжирования и фрагмента
+	var terms []searchTerm
+	for _, group := range q.Groups {
+		for _, condition := range group {
+			if !condition.Negate && condition.term.words != nil {
+				terms = append(terms, condition.term)
+			}
+		}
+	}
+	return terms
+}
+
+func (q *Query) match(task models.Task) bool { // проверка без бд, для хранилища в памяти
+	for _, group := range q.Groups {
+		matched := true
+		for _, condition := range group {
+			if condition.match(task) == condition.Negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Condition) match(task models.Task) bool { // условие без учета Negate
+	switch c.Field {
+	case FieldDate:
+		return task.Date == c.Value
+	case FieldBefore:
+		return task.Date < c.Value
+	case FieldAfter:
+		return task.Date > c.Value
+	case FieldRepeat:
+		return (task.Repeat != "") == (c.Value == "yes")
+	case FieldTag:
+		return slices.Contains(task.Tags, c.Value)
+	case FieldPriority:
+		return strconv.Itoa(task.Priority) == c.Value
+	case FieldBlocked:
+		return task.Blocked == (c.Value == "yes")
+	}
+	return c.term.found(task.Title, task.Comment)
+}
+
+// sql - условие WHERE с плейсхолдерами.
+func (q *Query) sql() (string, []any) {
+	var args []any
+	groups := make([]string, len(q.Groups))
+	for i, group := range q.Groups {
+		conditions := make([]string, len(group))
+		for j, condition := range group {
+			var sql string
+			switch condition.Field {
+			case FieldDate:
+				sql = "date = ?"
+			case FieldBefore:
+				sql = "date < ?"
+			case FieldAfter:
+				sql = "date > ?"
+			case FieldRepeat:
+				sql = "repeat <> ''"
+				if condition.Value == "no" {
+					sql = "repeat = ''"
+				}
+			case FieldTag:
+				sql = "id IN (SELECT task_id FROM tags WHERE tag = ?)"
+				args = append(args, condition.Value)
+			case FieldPriority:
+				priority, _ := strconv.Atoi(condition.Value)
+				sql = "priority = ?"
+				args = append(args, priority)
+			case FieldBlocked:
+				sql = blockedTask
+				if condition.Value == "no" {
+					sql = "NOT " + blockedTask
+				}
+			default:
+				var arg any
+				sql, arg = sqliteTextMatch(condition.term)
+				args = append(args, arg)
+			}
+			switch condition.Field {
+			case FieldDate, FieldBefore, FieldAfter:
+				args = append(args, condition.Value)
+			}
+			if condition.Negate {
+				sql = "NOT (" + sql + ")"
+			}
+			conditions[j] = sql
+		}
+		groups[i] = "(" + strings.Join(conditions, " AND ") + ")"
+	}
+	return "(" + strings.Join(groups, " OR ") + ")", args
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+)
+
+// TaskRepository - хранилище задач. TaskService проверяет и готовит данные,
+// репозиторий только читает и записывает их с учетом прав пользователя:
+// читать можно личные задачи и задачи общих списков, менять - с ролью editor или owner.
+// Задача, к которой нет доступа, не отличается от несуществующей:
+// GetTask возвращает sql.ErrNoRows, остальные методы - ошибку "task not found".
+// seriesDate в MoveTask и CompleteTask - дата серии повторений до переноса на рабочий день.
+type TaskRepository interface {
+	GetTasks(userID int64, filter TaskFilter) ([]models.Task, error)
+	GetTask(userID int64, id int) (*models.Task, error)
+	TaskRole(userID int64, id int) (string, error)       // роль пользователя для задачи, у личной задачи автор - owner
+	ListRole(userID int64, listID int64) (string, error) // роль в общем списке, пусто - не участник
+	AddTask(userID int64, task models.Task) (int64, error)
+	UpdateTask(userID int64, task models.Task) error // непустой task.ListID переносит задачу в другой список
+	MoveTask(userID int64, id int, nextDate string, seriesDate string, repeat string, completed bool) error
+	DeleteTask(userID int64, id int) error // удаление вместе с исключенными датами, метками и чек-листом; подзадачи остаются без родителя
+	DeleteListTasks(listID int64) error    // задачи и история удаляемого списка, роль owner проверяет ListService
+
+	// Чек-лист задачи taskID; пункт другой задачи - ErrItemNotFound.
+	AddChecklistItem(userID int64, taskID int, text string) (int64, error)
+	UpdateChecklistItem(userID int64, taskID int, item models.ChecklistItem) error // текст и отметка
+	ReorderChecklist(userID int64, taskID int, ids []int64) error                  // ids - все пункты в новом порядке
+	DeleteChecklistItem(userID int64, taskID int, id int64) error
+
+	// История выполнений видна так же, как задачи: автору личной задачи и участникам списка.
+	// Записи остаются после удаления задачи. CompleteTask в одной транзакции переносит задачу
+	// как MoveTask с completed (пустая nextDate удаляет ее) и записывает выполнение.
+	CompleteTask(userID int64, id int, nextDate string, seriesDate string, repeat string, completion models.Completion) (int64, error)
+	GetHistory(userID int64, filter HistoryFilter) ([]models.Completion, error) // новые записи первыми
+}
+
+type HistoryFilter struct { // условия выборки GetHistory
+	TaskID int64 // выполнения одной задачи, 0 - все
+	Before int64 // записи с id меньше этого, 0 - с последней
+	Limit  int
+}
+
+var ErrItemNotFound = errors.New("checklist item not found")
+
+type TaskFilter struct { // условия выборки GetTasks
+	Query  *Query // строка поиска, nil - все задачи
+	From   string // 20060102, задачи не раньше этой даты, пусто - без ограничения
+	To     string // 20060102, задачи не позже этой даты включительно
+	Parent int64  // только подзадачи этой задачи, 0 - все задачи
+	Sort   string // SortDate (по умолчанию), SortTitle, SortCreated или SortRelevance (при поиске по словам)
+	Desc   bool
+	After  *Cursor // задачи после курсора, его порядок важнее Sort и Desc
+	Limit  int
+}
+
+func New(db *database.DB) TaskRepository { // задачи в файле SQLite (TODO_DBFILE)
+	return &sqlRepository{db: db}
+}
+
+func ParseListID(listID string) (int64, error) { // пустой или "0" - личная задача
+	return parseID(listID, "incorrect list identifier")
+}
+
+func ParseParentID(parentID string) (int64, error) { // пустой или "0" - задача верхнего уровня
+	return parseID(parentID, "incorrect parent task identifier")
+}
+
+func parseID(value string, message string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.New(message)
+	}
+	return id, nil
+}
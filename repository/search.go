@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// searchTerm - слово или фраза из строки поиска. Слова ищутся по префиксу
+// ("куп" находит "Купить"), фразы в кавычках - целиком, слова подряд.
+type searchTerm struct {
+	words  []string // в нижнем регистре, только буквы и цифры
+	prefix bool     // последнее слово - префикс
+	column string   // title или comment, пусто - любая из колонок
+}
+
+const (
+	snippetStart = "\x01" // границы найденных слов во фрагменте из бд, в snippetHTML заменяются на <mark></mark>
+	snippetEnd   = "\x02"
+)
+
+var markReplacer = strings.NewReplacer(snippetStart, "<mark>", snippetEnd, "</mark>")
+
+// snippetHTML экранирует текст фрагмента и только потом выделяет найденные слова тегом <mark>:
+// заголовок и комментарий задачи общего списка не должны попасть к другим участникам как разметка.
+func snippetHTML(snippet string) string {
+	return markReplacer.Replace(html.EscapeString(snippet))
+}
+
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (term searchTerm) fts() string { // термин запроса FTS5 MATCH: title : "купить хлеб"*
+	match := `"` + strings.Join(term.words, " ") + `"`
+	if term.prefix {
+		match += "*"
+	}
+	if term.column != "" {
+		match = term.column + " : " + match
+	}
+	return match
+}
+
+func ftsQuery(terms []searchTerm) string { // задачи, где есть хотя бы один из терминов, для ранжирования
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term.fts()
+	}
+	return strings.Join(parts, " OR ")
+}
+
+type wordSpan struct { // слово текста и его границы в байтах
+	word       string
+	start, end int
+}
+
+func wordSpans(text string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i, r := range text + " " {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			spans = append(spans, wordSpan{word: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	return spans
+}
+
+func (term searchTerm) matchAt(spans []wordSpan, i int) bool { // термин начинается со слова spans[i]
+	if i+len(term.words) > len(spans) {
+		return false
+	}
+	for j, word := range term.words {
+		last := j == len(term.words)-1
+		if spans[i+j].word != word && !(last && term.prefix && strings.HasPrefix(spans[i+j].word, word)) {
+			return false
+		}
+	}
+	return true
+}
+
+// highlight выделяет найденные термины в колонке column и возвращает число совпадений.
+// Используется там, где нет полнотекстового индекса (хранилище в памяти).
+func highlight(text string, column string, terms []searchTerm) (string, int) {
+	spans := wordSpans(text)
+	var b strings.Builder
+	matches, pos := 0, 0
+	for i := 0; i < len(spans); i++ {
+		for _, term := range terms {
+			if (term.column != "" && term.column != column) || !term.matchAt(spans, i) {
+				continue
+			}
+			last := spans[i+len(term.words)-1]
+			b.WriteString(text[pos:spans[i].start])
+			b.WriteString(snippetStart + text[spans[i].start:last.end] + snippetEnd)
+			pos = last.end
+			i += len(term.words) - 1
+			matches++
+			break
+		}
+	}
+	b.WriteString(text[pos:])
+	return b.String(), matches
+}
+
+func (term searchTerm) found(title string, comment string) bool {
+	texts := []string{title, comment}
+	switch term.column {
+	case FieldTitle:
+		texts = texts[:1]
+	case FieldComment:
+		texts = texts[1:]
+	}
+	for _, text := range texts {
+		spans := wordSpans(text)
+		for i := range spans {
+			if term.matchAt(spans, i) {
+				return true
+			}
+		}
+	}
+	return false
+}
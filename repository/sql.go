@@ -0,0 +1,460 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+)
+
+// sqlRepository - реализация TaskRepository на SQLite.
+type sqlRepository struct {
+	db *database.DB
+}
+
+// blockedTask - у задачи есть блокирующая задача, которая еще не выполнена к ее дате:
+// разовая блокирует, пока существует, повторяющаяся - пока ее дата не позже даты задачи.
+const blockedTask = "EXISTS (SELECT 1 FROM dependencies JOIN scheduler blocker ON blocker.id = dependencies.blocker_id " +
+	"WHERE dependencies.task_id = scheduler.id AND (blocker.repeat = '' OR blocker.date <= scheduler.date))"
+
+const taskColumns = "id, date, title, comment, repeat, repeat_until, repeat_count, repeat_mode, list_id, priority, start_time, duration, parent_id, series_date, " +
+	"CASE WHEN " + blockedTask + " THEN 1 ELSE 0 END" // колонки для чтения задачи
+
+// Условия доступа к задачам: личные задачи пользователя и задачи общих списков, в которых он участвует
+// (для изменения - с ролью editor или owner). Оба условия принимают userID дважды.
+const (
+	visibleTasks  = "((list_id = 0 AND user_id = ?) OR list_id IN (SELECT list_id FROM list_members WHERE user_id = ?))"
+	editableTasks = "((list_id = 0 AND user_id = ?) OR list_id IN (SELECT list_id FROM list_members WHERE user_id = ? AND role IN ('editor', 'owner')))"
+)
+
+// subtaskIDs - подзадачи задачи на всех уровнях вложенности, принимает id задачи.
+const subtaskIDs = "WITH RECURSIVE subtasks(id) AS (SELECT id FROM scheduler WHERE parent_id = ? " +
+	"UNION SELECT scheduler.id FROM scheduler JOIN subtasks ON scheduler.parent_id = subtasks.id) SELECT id FROM subtasks"
+
+type scanner interface { // общий интерфейс *sql.Row и *sql.Rows
+	Scan(dest ...any) error
+}
+
+func scanTask(row scanner, extra ...any) (*models.Task, error) { // extra - колонки после taskColumns
+	var task models.Task
+	var blocked int
+	dest := []any{&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat, &task.RepeatUntil, &task.RepeatCount, &task.RepeatMode, &task.ListID, &task.Priority, &task.Time, &task.Duration, &task.ParentID, &task.SeriesDate, &blocked}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	task.Blocked = blocked != 0
+	if task.ListID == "0" { // личная задача
+		task.ListID = ""
+	}
+	if task.ParentID == "0" { // задача верхнего уровня
+		task.ParentID = ""
+	}
+	return &task, nil
+}
+
+func (r *sqlRepository) GetTasks(userID int64, filter TaskFilter) ([]models.Task, error) {
+	filter = filter.normalize()
+	columns, from := taskColumns, " FROM scheduler"
+	var args []any
+	var terms []searchTerm
+	if filter.Query != nil {
+		terms = filter.Query.textTerms()
+	}
+	if len(terms) > 0 { // ранжирование и фрагменты по словам без отрицания
+		columns += ", COALESCE(found.snippet, '')"
+		from += " LEFT JOIN (" + sqliteSearch + ") found ON found.task_id = scheduler.id"
+		args = append(args, ftsQuery(terms))
+	}
+	query := "SELECT " + columns + from + " WHERE " + visibleTasks
+	args = append(args, userID, userID)
+	if filter.From != "" {
+		query += " AND date >= ?"
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += " AND date <= ?"
+		args = append(args, filter.To)
+	}
+	if filter.Parent != 0 {
+		query += " AND parent_id = ?"
+		args = append(args, filter.Parent)
+	}
+	if filter.Query != nil {
+		where, whereArgs := filter.Query.sql()
+		query += " AND " + where
+		args = append(args, whereArgs...)
+	}
+	if filter.After != nil && filter.After.Sort != SortRelevance {
+		where, whereArgs := filter.keysetSQL()
+		query += " AND " + where
+		args = append(args, whereArgs...)
+	}
+	query += filter.orderSQL()
+	if offset := filter.offset(); offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = math.MaxInt32 // OFFSET в SQLite допустим только после LIMIT
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	} else if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var snippet string
+		var extra []any
+		if len(terms) > 0 {
+			extra = append(extra, &snippet)
+		}
+		task, err := scanTask(rows, extra...)
+		if err != nil {
+			return nil, err
+		}
+		task.Snippet = snippetHTML(snippet)
+		tasks = append(tasks, *task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadDetails(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (r *sqlRepository) GetTask(userID int64, id int) (*models.Task, error) {
+	task, err := scanTask(r.db.QueryRow("SELECT "+taskColumns+" FROM scheduler WHERE id = ? AND "+visibleTasks, id, userID, userID))
+	if err != nil {
+		return nil, err
+	}
+	tasks := []models.Task{*task}
+	if err := r.loadDetails(tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+func (r *sqlRepository) TaskRole(userID int64, id int) (string, error) {
+	var listID, ownerID int64
+	err := r.db.QueryRow("SELECT list_id, user_id FROM scheduler WHERE id = ?", id).Scan(&listID, &ownerID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("task not found")
+	} else if err != nil {
+		return "", err
+	}
+	if listID == 0 {
+		if ownerID != userID {
+			return "", fmt.Errorf("task not found")
+		}
+		return models.RoleOwner, nil
+	}
+	role, err := r.ListRole(userID, listID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", fmt.Errorf("task not found")
+	}
+	return role, nil
+}
+
+func (r *sqlRepository) ListRole(userID int64, listID int64) (string, error) {
+	var role string
+	err := r.db.QueryRow("SELECT role FROM list_members WHERE list_id = ? AND user_id = ?", listID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+func (r *sqlRepository) AddTask(userID int64, task models.Task) (int64, error) {
+	listID, err := ParseListID(task.ListID)
+	if err != nil {
+		return 0, err
+	}
+	parentID, err := ParseParentID(task.ParentID)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	query := `INSERT INTO scheduler (date, title, comment, repeat, repeat_until, repeat_count, repeat_mode, user_id, list_id, priority, start_time, duration, parent_id, series_date) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	err = tx.QueryRow(query, task.Date, task.Title, task.Comment, task.Repeat, task.RepeatUntil, task.RepeatCount, task.RepeatMode, userID, listID, task.Priority, task.Time, task.Duration, parentID, task.SeriesDate).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if err := saveExdates(tx, id, task.Exdates); err != nil {
+		return 0, err
+	}
+	if err := saveTags(tx, id, task.Tags); err != nil {
+		return 0, err
+	}
+	if err := saveDependencies(tx, id, task.BlockedBy); err != nil {
+		return 0, err
+	}
+	for i, item := range task.Checklist { // начальные пункты, дальше чек-лист меняется отдельными методами
+		if _, err := tx.Exec("INSERT INTO checklist (task_id, position, text, done) VALUES (?, ?, ?, ?)", id, i+1, item.Text, doneFlag(item.Done)); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+func (r *sqlRepository) UpdateTask(userID int64, task models.Task) error {
+	parentID, err := ParseParentID(task.ParentID)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, repeat_until = ?, repeat_count = ?, repeat_mode = ?, priority = ?, start_time = ?, duration = ?, parent_id = ?, series_date = ? WHERE id = ? AND "+editableTasks,
+		task.Date, task.Title, task.Comment, task.Repeat, task.RepeatUntil, task.RepeatCount, task.RepeatMode, task.Priority, task.Time, task.Duration, parentID, task.SeriesDate, task.ID, userID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("task not found")
+	}
+	if task.ListID != "" { // перенос в другой список или в личные задачи, пустой list_id оставляет задачу в ее списке
+		listID, err := ParseListID(task.ListID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE scheduler SET list_id = ?, user_id = ? WHERE id = ?", listID, userID, task.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE scheduler SET list_id = ?, user_id = ? WHERE id IN ("+subtaskIDs+")", listID, userID, task.ID); err != nil { // подзадачи переезжают вместе с задачей
+			return err
+		}
+	}
+	if err := saveExdates(tx, task.ID, task.Exdates); err != nil {
+		return err
+	}
+	if err := saveTags(tx, task.ID, task.Tags); err != nil {
+		return err
+	}
+	if err := saveDependencies(tx, task.ID, task.BlockedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MoveTask переносит задачу на следующую дату. У выполненной (completed) задачи
+// оставшееся количество повторений уменьшается, 0 остается без ограничения.
+func (r *sqlRepository) MoveTask(userID int64, id int, nextDate string, seriesDate string, repeat string, completed bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := moveTask(tx, userID, id, nextDate, seriesDate, repeat, completed); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func moveTask(tx *database.Tx, userID int64, id int, nextDate string, seriesDate string, repeat string, completed bool) error { // MoveTask в транзакции tx
+	query := "UPDATE scheduler SET date = ?, series_date = ?, repeat = ? WHERE id = ? AND " + editableTasks
+	if completed { // у следующего повторения чек-лист снова не отмечен
+		query = "UPDATE scheduler SET date = ?, series_date = ?, repeat = ?, repeat_count = CASE WHEN repeat_count > 0 THEN repeat_count - 1 ELSE 0 END WHERE id = ? AND " + editableTasks
+	}
+	result, err := tx.Exec(query, nextDate, seriesDate, repeat, id, userID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("task not found")
+	}
+	if _, err := tx.Exec("DELETE FROM exdates WHERE task_id = ? AND date < ?", id, nextDate); err != nil { // прошедшие исключения больше не нужны
+		return err
+	}
+	if completed {
+		if _, err := tx.Exec("UPDATE checklist SET done = 0 WHERE task_id = ?", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlRepository) DeleteTask(userID int64, id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTask(tx, userID, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepository) DeleteListTasks(listID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		"DELETE FROM exdates WHERE task_id IN (SELECT id FROM scheduler WHERE list_id = ?)",
+		"DELETE FROM tags WHERE task_id IN (SELECT id FROM scheduler WHERE list_id = ?)",
+		"DELETE FROM checklist WHERE task_id IN (SELECT id FROM scheduler WHERE list_id = ?)",
+		"DELETE FROM dependencies WHERE task_id IN (SELECT id FROM scheduler WHERE list_id = ?)",
+		"DELETE FROM dependencies WHERE blocker_id IN (SELECT id FROM scheduler WHERE list_id = ?)",
+		"DELETE FROM history WHERE list_id = ?",
+		"DELETE FROM scheduler WHERE list_id = ?",
+	} {
+		if _, err := tx.Exec(query, listID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func deleteTask(tx *database.Tx, userID int64, id int) error { // DeleteTask в транзакции tx
+	result, err := tx.Exec("DELETE FROM scheduler WHERE id = ? AND "+editableTasks, id, userID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("task not found")
+	}
+	if _, err := tx.Exec("DELETE FROM exdates WHERE task_id = ?", id); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		"DELETE FROM tags WHERE task_id = ?",
+		"DELETE FROM checklist WHERE task_id = ?",
+		"UPDATE scheduler SET parent_id = 0 WHERE parent_id = ?",
+		"DELETE FROM dependencies WHERE task_id = ?",
+		"DELETE FROM dependencies WHERE blocker_id = ?", // выполненная или удаленная задача больше не блокирует
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveExdates(tx *database.Tx, id any, exdates []string) error { // заменяет исключенные даты задачи
+	if _, err := tx.Exec("DELETE FROM exdates WHERE task_id = ?", id); err != nil {
+		return err
+	}
+	for _, date := range exdates {
+		if _, err := tx.Exec("INSERT INTO exdates (task_id, date) VALUES (?, ?)", id, date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveTags(tx *database.Tx, id any, tags []string) error { // заменяет метки задачи
+	if _, err := tx.Exec("DELETE FROM tags WHERE task_id = ?", id); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT INTO tags (task_id, tag) VALUES (?, ?)", id, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveDependencies(tx *database.Tx, id any, blockedBy []string) error { // заменяет блокирующие задачи
+	if _, err := tx.Exec("DELETE FROM dependencies WHERE task_id = ?", id); err != nil {
+		return err
+	}
+	for _, blocker := range blockedBy {
+		if _, err := tx.Exec("INSERT INTO dependencies (task_id, blocker_id) VALUES (?, ?)", id, blocker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlRepository) loadDetails(tasks []models.Task) error { // исключенные даты, метки, зависимости и чек-лист из отдельных таблиц
+	err := r.loadValues(tasks, "SELECT task_id, date FROM exdates WHERE task_id IN (%s) ORDER BY date", func(task *models.Task, date string) {
+		task.Exdates = append(task.Exdates, date)
+	})
+	if err != nil {
+		return err
+	}
+	err = r.loadValues(tasks, "SELECT task_id, tag FROM tags WHERE task_id IN (%s) ORDER BY tag", func(task *models.Task, tag string) {
+		task.Tags = append(task.Tags, tag)
+	})
+	if err != nil {
+		return err
+	}
+	err = r.loadValues(tasks, "SELECT task_id, blocker_id FROM dependencies WHERE task_id IN (%s) ORDER BY blocker_id", func(task *models.Task, id string) {
+		task.BlockedBy = append(task.BlockedBy, id)
+	})
+	if err != nil {
+		return err
+	}
+	return r.loadChecklist(tasks)
+}
+
+// loadValues заполняет поле задач одним запросом: query выбирает task_id и значение,
+// вместо %s подставляются плейсхолдеры идентификаторов.
+func (r *sqlRepository) loadValues(tasks []models.Task, query string, add func(task *models.Task, value string)) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]any, len(tasks))
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+		index[task.ID] = i
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	rows, err := r.db.Query(fmt.Sprintf(query, placeholders), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return err
+		}
+		if i, ok := index[id]; ok {
+			add(&tasks[i], value)
+		}
+	}
+	return rows.Err()
+}
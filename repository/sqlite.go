@@ -0,0 +1,10 @@
+package repository
+
+// поиск по индексу FTS5 scheduler_fts (миграция 0002_search), заголовок весит больше комментария
+const sqliteSearch = `SELECT rowid AS task_id, bm25(scheduler_fts, 10.0, 1.0) AS search_rank,
+	snippet(scheduler_fts, -1, '` + snippetStart + `', '` + snippetEnd + `', '…', 12) AS snippet
+	FROM scheduler_fts WHERE scheduler_fts MATCH ?`
+
+func sqliteTextMatch(term searchTerm) (string, any) {
+	return "id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?)", term.fts()
+}
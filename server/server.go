@@ -5,46 +5,144 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/rust2014/go_final_project/auth"
 	"github.com/rust2014/go_final_project/database"
 	"github.com/rust2014/go_final_project/handlers"
+	"github.com/rust2014/go_final_project/holidays"
+	"github.com/rust2014/go_final_project/repository"
 
 	_ "modernc.org/sqlite"
 )
 
-func Run() {
-	db, err := database.ConnectDB() // запуск бд
+// Services - сервисы, с которыми работают обработчики API.
+type Services struct {
+	Tasks    *services.TaskService
+	Users    *services.UserService
+	Tokens   *services.TokenService
+	Lists    *services.ListService
+	Holidays *services.HolidayService
+}
+
+func NewServices(db *database.DB, repo repository.TaskRepository) Services {
+	s := Services{
+		Tasks:    services.NewTaskService(repo, time.Now), // задачи в SQLite или в памяти (--memory)
+		Users:    services.NewUserService(db),             // пользователи многопользовательского режима (TODO_MULTIUSER)
+		Tokens:   services.NewTokenService(db),            // личные API-токены
+		Lists:    services.NewListService(db),             // общие списки задач
+		Holidays: services.NewHolidayService(db),          // праздники для правил с модификатором рабочих дней
+	}
+	s.Lists.Tasks = repo
+	s.Tasks.CascadeDone = os.Getenv("TODO_SUBTASK_DONE") == "cascade" // иначе задачу с открытыми подзадачами выполнить нельзя
+	return s
+}
+
+func Run(memory bool) { // memory - задачи хранятся в памяти, остальное в SQLite в памяти, на диск ничего не пишется
+	var s Services
+	if memory {
+		db, err := database.OpenMemory()
+		if err != nil {
+			log.Fatalf("Ошибка при создании бд в памяти: %v", err)
+		}
+		defer db.Close()
+		s = NewServices(db, repository.NewMemory(services.NewListService(db))) // роли в общих списках берутся из бд в памяти
+		log.Printf("Демо-режим: данные хранятся в памяти и пропадут после остановки сервера")
+	} else {
+		db, err := database.ConnectDB() // запуск бд
+		if err != nil {
+			log.Fatalf("Ошибка при подключении к базе данных: %v", err)
+		}
+		defer db.Close() // закрытие бд
+		s = NewServices(db, repository.New(db))
+	}
+
+	key, err := s.Users.SigningKey() // токены подписываются ключом из бд, если не задан TODO_JWT_SECRET
 	if err != nil {
-		log.Fatalf("Ошибка при подключении к базе данных: %v", err)
+		log.Fatalf("Ошибка при загрузке ключа подписи: %v", err)
 	}
-	defer db.Close() // закрытие бд
+	auth.SetSigningKey(key)
 
-	taskService := services.NewTaskService(db) // Инициализация TaskService
+	if path := os.Getenv("TODO_HOLIDAYS"); path != "" { // файл праздников в формате iCal (.ics) или CSV
+		list, err := holidays.LoadFile(path)
+		if err != nil {
+			log.Fatalf("Ошибка при загрузке праздников: %v", err)
+		}
+		if err := s.Holidays.AddHolidays(list); err != nil {
+			log.Fatalf("Ошибка при сохранении праздников: %v", err)
+		}
+		log.Printf("Загружено праздников из %s: %d", path, len(list))
+	}
+	if err := s.Holidays.Reload(); err != nil {
+		log.Fatalf("Ошибка при загрузке праздников: %v", err)
+	}
 
 	port := os.Getenv("TODO_PORT") // если переменная окружения TODO_PORT не установлена, сервер будет запущен на порту 7540
 	if port == "" {
 		port = "7540" // порт по умолчанию
 	}
-	webDir := "./web" // каталог с вебом
+
+	log.Printf("Starting server at port %s", port)             // сообщение о старте + порт
+	err = http.ListenAndServe(":"+port, NewRouter(s, "./web")) // запуск сервера на нашем порте из переменной port
+	if err != nil {
+		log.Fatal(err) // для логирования ошибки
+	}
+}
+
+func NewRouter(s Services, webDir string) http.Handler { // маршруты API и статика из webDir (каталог с вебом)
 	fileServer := http.FileServer(http.Dir(webDir))
 
 	router := chi.NewRouter()
 	router.Handle("/*", fileServer) // обработчик файлов
 
-	router.Get("/api/nextdate", handlers.NextDateHandler) // Правила повторения задач, обработчик для вычисления следующей даты (3)
+	router.Get("/api/nextdate", handlers.NextDateHandler)                // Правила повторения задач, обработчик для вычисления следующей даты (3)
+	router.Get("/api/occurrences", handlers.OccurrencesHandler(s.Tasks)) // предпросмотр ближайших дат повторения
+	router.Get("/api/describe", handlers.DescribeHandler)                // описание правила повторения на русском или английском
 
-	router.Post("/api/task", handlers.HandlerTask(taskService))          // добавляем задачу в бд - AddTask (4)
-	router.Get("/api/task", handlers.HandlerGetTask(taskService))        // просмотр задачи (6)
-	router.Put("/api/task", handlers.HandlerPutTask(taskService))        // редактирование задачи (6)
-	router.Post("/api/task/done", handlers.HandlerDoneTask(taskService)) // завершение задачи (7)
-	router.Delete("/api/task", handlers.HandlerDeleteTask(taskService))  // удаление задачи (7)
+	router.Post("/api/signin", handlers.HandlerSignIn(s.Users)) // вход по паролю TODO_PASSWORD или логину и паролю пользователя, выдает токен
+	router.Post("/api/signup", handlers.HandlerSignUp(s.Users)) // регистрация пользователя в многопользовательском режиме
 
-	router.Get("/api/tasks", handlers.HandlerGetTasks(taskService)) // Получаем список ближайших задач в вебе (5)
+	router.Group(func(r chi.Router) { // маршруты, требующие токен, если задан TODO_PASSWORD или TODO_MULTIUSER
+		r.Use(auth.Middleware(s.Users, s.Tokens))
 
-	log.Printf("Starting server at port %s", port) // сообщение о старте + порт
-	err = http.ListenAndServe(":"+port, router)    // запуск сервера на нашем порте из переменной port
-	if err != nil {
-		log.Fatal(err) // для логирования ошибки
-	}
+		r.Post("/api/task", handlers.HandlerTask(s.Tasks))          // добавляем задачу в бд - AddTask (4)
+		r.Get("/api/task", handlers.HandlerGetTask(s.Tasks))        // просмотр задачи (6)
+		r.Put("/api/task", handlers.HandlerPutTask(s.Tasks))        // редактирование задачи (6)
+		r.Post("/api/task/done", handlers.HandlerDoneTask(s.Tasks)) // завершение задачи (7)
+		r.Post("/api/task/skip", handlers.HandlerSkipTask(s.Tasks)) // пропуск повторения задачи без выполнения
+		r.Delete("/api/task", handlers.HandlerDeleteTask(s.Tasks))  // удаление задачи (7)
+
+		r.Post("/api/task/checklist", handlers.HandlerAddChecklistItem(s.Tasks))      // пункт чек-листа в конец
+		r.Put("/api/task/checklist", handlers.HandlerPutChecklistItem(s.Tasks))       // текст и отметка пункта
+		r.Put("/api/task/checklist/order", handlers.HandlerReorderChecklist(s.Tasks)) // новый порядок пунктов
+		r.Delete("/api/task/checklist", handlers.HandlerDeleteChecklistItem(s.Tasks)) // удаление пункта
+
+		r.Get("/api/tasks", handlers.HandlerGetTasks(s.Tasks))           // Получаем список ближайших задач в вебе (5)
+		r.Get("/api/task/history", handlers.HandlerTaskHistory(s.Tasks)) // выполнения задачи, в том числе удаленной
+		r.Get("/api/history", handlers.HandlerHistory(s.Tasks))          // лента выполнений всех задач
+
+		r.Get("/api/holidays", handlers.HandlerGetHolidays(s.Holidays)) // список праздников
+
+		r.Group(func(r chi.Router) { // праздники общие для всех пользователей, менять их может только администратор
+			r.Use(auth.AdminOnly)
+			r.Post("/api/holidays", handlers.HandlerAddHoliday(s.Holidays))            // добавление праздника
+			r.Post("/api/holidays/import", handlers.HandlerImportHolidays(s.Holidays)) // загрузка праздников из iCal или CSV
+			r.Delete("/api/holidays", handlers.HandlerDeleteHoliday(s.Holidays))       // удаление праздника
+		})
+
+		r.Get("/api/tokens", handlers.HandlerGetTokens(s.Tokens))      // список личных API-токенов
+		r.Post("/api/tokens", handlers.HandlerCreateToken(s.Tokens))   // создание токена для Authorization: Bearer
+		r.Delete("/api/tokens", handlers.HandlerRevokeToken(s.Tokens)) // отзыв токена
+
+		r.Get("/api/lists", handlers.HandlerGetLists(s.Lists))                // общие списки пользователя с его ролью
+		r.Post("/api/lists", handlers.HandlerAddList(s.Lists))                // создание списка, создатель - owner
+		r.Put("/api/lists", handlers.HandlerPutList(s.Lists))                 // переименование списка
+		r.Delete("/api/lists", handlers.HandlerDeleteList(s.Lists))           // удаление списка с задачами
+		r.Get("/api/lists/members", handlers.HandlerGetMembers(s.Lists))      // участники списка
+		r.Post("/api/lists/members", handlers.HandlerShareList(s.Lists))      // доступ пользователю: viewer, editor или owner
+		r.Delete("/api/lists/members", handlers.HandlerRemoveMember(s.Lists)) // удаление участника или выход из списка
+	})
+
+	return router
 }
@@ -7,10 +7,11 @@ import (
 
 	"github.com/rust2014/go_final_project/dates"
 	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
 	"github.com/rust2014/go_final_project/validation"
 )
 
-func (s *TaskService) AddTask(task models.Task) (int64, error) { // создание новой задачи в бд
+func (s *TaskService) AddTask(userID int64, task models.Task) (int64, error) { // создание новой задачи в бд
 	// Логирование входных данных для диагностики ошибок
 	fmt.Printf("Task Date: %s\n", task.Date)
 	fmt.Printf("Task Title: %s\n", task.Title)
@@ -25,7 +26,41 @@ func (s *TaskService) AddTask(task models.Task) (int64, error) { // создан
 		return 0, errors.New("no task title")
 	}
 
-	now := time.Now()
+	repeat, err := validation.NormalizeRepeatRule(task.Repeat) // правило хранится в каноническом виде
+	if err != nil {
+		return 0, err
+	}
+	task.Repeat = repeat
+
+	task.Exdates, err = validation.NormalizeExdates(task.Repeat, task.Exdates)
+	if err != nil {
+		return 0, err
+	}
+
+	task.RepeatMode, err = validation.NormalizeRepeatMode(task.RepeatMode)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := validation.ValidatePriority(task.Priority); err != nil {
+		return 0, err
+	}
+
+	task.Tags, err = validation.NormalizeTags(task.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := validation.ValidateTime(task.Time, task.Duration); err != nil {
+		return 0, err
+	}
+
+	task.Checklist, err = validation.NormalizeChecklist(task.Checklist)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.Now()
 	if task.Date == "" || task.Date == "today" {
 		task.Date = now.Format(dates.DefaultDateFormat)
 	} else {
@@ -40,30 +75,40 @@ func (s *TaskService) AddTask(task models.Task) (int64, error) { // создан
 				if task.Repeat == "d 1" {
 					task.Date = now.Format(dates.DefaultDateFormat)
 				} else {
-					nextDate, err := dates.NextDate(now, task.Date, task.Repeat)
+					nextDate, seriesDate, repeat, err := dates.NextSeriesDate(now, task.Date, "", task.Repeat, task.Exdates)
 					if err != nil {
 						return 0, err
 					}
-					task.Date = nextDate
+					task.Date, task.SeriesDate = nextDate, seriesDate
+					task.Repeat = repeat // у RRULE с COUNT учитываются пропущенные повторения
 				}
 			}
 		}
 	}
 
-	if err := validation.ValidateRepeatRule(task.Repeat); err != nil {
-		fmt.Println(err)
+	if err := validation.ValidateRepeatEnd(task.Date, task.Repeat, task.RepeatUntil, task.RepeatCount); err != nil {
 		return 0, err
 	}
 
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`
-	res, err := s.DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
+	if err := s.checkParent(userID, &task); err != nil {
+		return 0, err
+	}
+	if err := s.checkBlockers(userID, &task); err != nil {
+		return 0, err
+	}
+	listID, err := repository.ParseListID(task.ListID)
 	if err != nil {
-		fmt.Println("Error executing query:", err)
 		return 0, err
 	}
-	id, err := res.LastInsertId() // Получение ID
+	if listID != 0 { // добавлять задачи в общий список могут editor и owner
+		if err := s.requireListRole(userID, listID, models.RoleEditor); err != nil {
+			return 0, err
+		}
+	}
+
+	id, err := s.Repo.AddTask(userID, task)
 	if err != nil {
-		fmt.Println("Error getting last insert ID:", err)
+		fmt.Println("Error executing query:", err)
 		return 0, err
 	}
 	fmt.Println("Inserted task with ID:", id)
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/models"
+)
+
+var ErrInvalidDependency = errors.New("invalid task dependency")
+
+// checkBlockers проверяет блокирующие задачи: они доступны пользователю и не образуют
+// цикл, в котором задача в итоге ждет саму себя. BlockedBy сортируется по id без повторов.
+func (s *TaskService) checkBlockers(userID int64, task *models.Task) error {
+	if len(task.BlockedBy) == 0 {
+		task.BlockedBy = nil
+		return nil
+	}
+	var ids []int
+	for _, value := range task.BlockedBy {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			return fmt.Errorf("%w: incorrect identifier %q", ErrInvalidDependency, value)
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if len(ids) > models.MaxBlockers {
+		return fmt.Errorf("%w: too many blocking tasks", ErrInvalidDependency)
+	}
+
+	task.BlockedBy = make([]string, len(ids))
+	seen := map[string]bool{}
+	for i, id := range ids {
+		task.BlockedBy[i] = strconv.Itoa(id)
+		if task.BlockedBy[i] == task.ID {
+			return fmt.Errorf("%w: a task cannot block itself", ErrInvalidDependency)
+		}
+		blocker, err := s.Repo.GetTask(userID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: blocking task %d not found", ErrInvalidDependency, id)
+		}
+		if err != nil {
+			return err
+		}
+		if task.ID == "" { // новую задачу еще никто не ждет
+			continue
+		}
+		waits, err := s.waitsFor(userID, blocker, task.ID, seen)
+		if err != nil {
+			return err
+		}
+		if waits {
+			return fmt.Errorf("%w: task %d already waits for this task", ErrInvalidDependency, id)
+		}
+	}
+	return nil
+}
+
+// waitsFor - задача task прямо или через другие задачи заблокирована задачей target.
+// Недоступные пользователю задачи пропускаются, seen - уже проверенные задачи.
+func (s *TaskService) waitsFor(userID int64, task *models.Task, target string, seen map[string]bool) (bool, error) {
+	for _, value := range task.BlockedBy {
+		if value == target {
+			return true, nil
+		}
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		id, _ := strconv.Atoi(value)
+		blocker, err := s.Repo.GetTask(userID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if waits, err := s.waitsFor(userID, blocker, target, seen); err != nil || waits {
+			return waits, err
+		}
+	}
+	return false, nil
+}
+
+// OpenBlockers - блокирующие задачи, которые еще не выполнены к дате задачи (правило blocked).
+func (s *TaskService) OpenBlockers(userID int64, task *models.Task) ([]models.Task, error) {
+	var blockers []models.Task
+	for _, value := range task.BlockedBy {
+		id, _ := strconv.Atoi(value)
+		blocker, err := s.Repo.GetTask(userID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if blocker.Repeat == "" || blocker.Date <= task.Date {
+			blockers = append(blockers, *blocker)
+		}
+	}
+	return blockers, nil
+}
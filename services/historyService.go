@@ -0,0 +1,47 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+)
+
+// TaskHistory - выполнения задачи, новые первыми. Удаленная задача находится по истории,
+// sql.ErrNoRows - нет ни задачи, ни ее выполнений.
+func (s *TaskService) TaskHistory(userID int64, id int) ([]models.Completion, error) {
+	history, err := s.Repo.GetHistory(userID, repository.HistoryFilter{TaskID: int64(id)})
+	if err != nil || len(history) > 0 {
+		return history, err
+	}
+	if _, err := s.Repo.GetTask(userID, id); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// History - лента выполнений всех доступных задач, новые первыми, и курсор следующей страницы
+// (пусто - записей больше нет). Ошибки параметров - ErrInvalidFilter.
+func (s *TaskService) History(userID int64, cursor string, limit int) ([]models.Completion, string, error) {
+	if limit < 0 || limit > maxTaskLimit {
+		return nil, "", fmt.Errorf("%w: limit must be from 1 to %d", ErrInvalidFilter, maxTaskLimit)
+	}
+	if limit == 0 {
+		limit = taskLimit
+	}
+	filter := repository.HistoryFilter{Limit: limit + 1} // лишняя запись показывает, что есть следующая страница
+	if cursor != "" {
+		before, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || before < 1 {
+			return nil, "", fmt.Errorf("%w: cursor %q", ErrInvalidFilter, cursor)
+		}
+		filter.Before = before
+	}
+	history, err := s.Repo.GetHistory(userID, filter)
+	if err != nil || len(history) <= limit {
+		return history, "", err
+	}
+	history = history[:limit]
+	return history, strconv.FormatInt(history[limit-1].ID, 10), nil
+}
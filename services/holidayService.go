@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/dates"
+	"github.com/rust2014/go_final_project/models"
+)
+
+type HolidayService struct { // праздники производственного календаря
+	DB *database.DB
+}
+
+func NewHolidayService(db *database.DB) *HolidayService {
+	return &HolidayService{DB: db}
+}
+
+func (s *HolidayService) GetHolidays() ([]models.Holiday, error) {
+	rows, err := s.DB.Query("SELECT date, name FROM holidays ORDER BY date")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holidays := []models.Holiday{}
+	for rows.Next() {
+		var holiday models.Holiday
+		if err := rows.Scan(&holiday.Date, &holiday.Name); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, holiday)
+	}
+	return holidays, rows.Err()
+}
+
+func (s *HolidayService) AddHolidays(holidays map[string]string) error { // добавляет или переименовывает праздники
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for date, name := range holidays {
+		_, err := tx.Exec("INSERT INTO holidays (date, name) VALUES (?, ?) ON CONFLICT(date) DO UPDATE SET name = excluded.name", date, name)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return s.Reload()
+}
+
+func (s *HolidayService) DeleteHoliday(date string) error {
+	result, err := s.DB.Exec("DELETE FROM holidays WHERE date = ?", date)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("holiday not found")
+	}
+	return s.Reload()
+}
+
+func (s *HolidayService) Reload() error { // загружает праздники из бд в календарь рабочих дней
+	holidays, err := s.GetHolidays()
+	if err != nil {
+		return err
+	}
+	calendar := make(map[string]string, len(holidays))
+	for _, holiday := range holidays {
+		calendar[holiday.Date] = holiday.Name
+	}
+	dates.WorkCalendar.SetHolidays(calendar)
+	return nil
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+)
+
+var (
+	ErrListNotFound = errors.New("list not found")
+	ErrForbidden    = errors.New("insufficient permissions")
+	ErrLastOwner    = errors.New("the list must have at least one owner")
+)
+
+type queryer interface { // общий интерфейс *database.DB и *database.Tx
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func listRole(db queryer, userID int64, listID int64) (string, error) { // роль пользователя в списке, пусто - не участник
+	var role string
+	err := db.QueryRow("SELECT role FROM list_members WHERE list_id = ? AND user_id = ?", listID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+func requireRole(current string, role string) error { // роль current не ниже role
+	if current == "" { // чужой список не отличается от несуществующего
+		return ErrListNotFound
+	}
+	if models.RoleRank(current) < models.RoleRank(role) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func requireListRole(db queryer, userID int64, listID int64, role string) error {
+	current, err := listRole(db, userID, listID)
+	if err != nil {
+		return err
+	}
+	return requireRole(current, role)
+}
+
+type ListService struct { // общие списки задач и их участники
+	DB    *database.DB
+	Tasks repository.TaskRepository // хранилище задач: задачи удаляемого списка удаляются через него, в том числе в памяти
+}
+
+func NewListService(db *database.DB) *ListService {
+	return &ListService{DB: db}
+}
+
+func (s *ListService) ListRole(userID int64, listID int64) (string, error) { // роль пользователя в списке для repository.NewMemory
+	return listRole(s.DB, userID, listID)
+}
+
+func validListName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 256 {
+		return "", errors.New("invalid list name")
+	}
+	return name, nil
+}
+
+func (s *ListService) CreateList(userID int64, name string) (int64, error) { // создатель становится владельцем списка
+	name, err := validListName(name)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	if err := tx.QueryRow("INSERT INTO lists (name) VALUES (?) RETURNING id", name).Scan(&id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("INSERT INTO list_members (list_id, user_id, role) VALUES (?, ?, ?)", id, userID, models.RoleOwner); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (s *ListService) GetLists(userID int64) ([]models.List, error) { // списки, в которых участвует пользователь
+	rows, err := s.DB.Query("SELECT l.id, l.name, m.role FROM lists l JOIN list_members m ON m.list_id = l.id WHERE m.user_id = ? ORDER BY l.name, l.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []models.List{}
+	for rows.Next() {
+		var list models.List
+		if err := rows.Scan(&list.ID, &list.Name, &list.Role); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+func (s *ListService) RenameList(userID int64, listID int64, name string) error {
+	name, err := validListName(name)
+	if err != nil {
+		return err
+	}
+	if err := requireListRole(s.DB, userID, listID, models.RoleOwner); err != nil {
+		return err
+	}
+	_, err = s.DB.Exec("UPDATE lists SET name = ? WHERE id = ?", name, listID)
+	return err
+}
+
+func (s *ListService) DeleteList(userID int64, listID int64) error { // удаляет список вместе с его задачами
+	if err := requireListRole(s.DB, userID, listID, models.RoleOwner); err != nil {
+		return err
+	}
+	if err := s.Tasks.DeleteListTasks(listID); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		"DELETE FROM list_members WHERE list_id = ?",
+		"DELETE FROM lists WHERE id = ?",
+	} {
+		if _, err := tx.Exec(query, listID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *ListService) GetMembers(userID int64, listID int64) ([]models.ListMember, error) { // участников видит любой участник списка
+	if err := requireListRole(s.DB, userID, listID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query("SELECT m.user_id, COALESCE(u.login, ''), m.role FROM list_members m LEFT JOIN users u ON u.id = m.user_id WHERE m.list_id = ? ORDER BY m.user_id", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.ListMember{}
+	for rows.Next() {
+		var member models.ListMember
+		if err := rows.Scan(&member.UserID, &member.Login, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *ListService) ShareList(userID int64, listID int64, login string, role string) error { // выдает или меняет роль пользователя с логином login
+	if models.RoleRank(role) == 0 {
+		return errors.New("invalid role")
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireListRole(tx, userID, listID, models.RoleOwner); err != nil {
+		return err
+	}
+	var memberID int64
+	err = tx.QueryRow("SELECT id FROM users WHERE login = ?", strings.TrimSpace(login)).Scan(&memberID)
+	if err == sql.ErrNoRows {
+		return errors.New("user not found")
+	} else if err != nil {
+		return err
+	}
+	if err := checkLastOwner(tx, listID, memberID, role); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO list_members (list_id, user_id, role) VALUES (?, ?, ?) ON CONFLICT (list_id, user_id) DO UPDATE SET role = excluded.role",
+		listID, memberID, role)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *ListService) RemoveMember(userID int64, listID int64, memberID int64) error { // владелец удаляет участника, участник может выйти сам
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	required := models.RoleOwner
+	if memberID == userID {
+		required = models.RoleViewer
+	}
+	if err := requireListRole(tx, userID, listID, required); err != nil {
+		return err
+	}
+	if err := checkLastOwner(tx, listID, memberID, ""); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM list_members WHERE list_id = ? AND user_id = ?", listID, memberID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("user not found")
+	}
+	return tx.Commit()
+}
+
+func checkLastOwner(db queryer, listID int64, memberID int64, newRole string) error { // у списка должен остаться владелец
+	if newRole == models.RoleOwner {
+		return nil
+	}
+	var others int
+	err := db.QueryRow("SELECT COUNT(*) FROM list_members WHERE list_id = ? AND role = ? AND user_id <> ?", listID, models.RoleOwner, memberID).Scan(&others)
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
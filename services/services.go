@@ -2,108 +2,254 @@ package services
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/rust2014/go_final_project/dates"
 	"github.com/rust2014/go_final_project/models"
-	"github.com/rust2014/go_final_project/tests"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/validation"
 )
 
+const (
+	taskLimit    = 50  // задач в ответе /api/tasks по умолчанию
+	maxTaskLimit = 500 // больше за один запрос не отдается, остальное - по next_cursor
+)
+
+var ErrInvalidFilter = errors.New("invalid task filter")
+
+type Clock func() time.Time // текущее время; в тестах - фиксированная дата
+
 type TaskService struct {
-	DB *sql.DB
+	Repo repository.TaskRepository
+	Now  Clock // от него считаются today, this week и другие относительные даты
+
+	CascadeDone bool // TODO_SUBTASK_DONE=cascade: выполнение задачи выполняет ее открытые подзадачи, иначе запрещено
 }
 
-func NewTaskService(db *sql.DB) *TaskService {
-	return &TaskService{DB: db}
+func NewTaskService(repo repository.TaskRepository, now Clock) *TaskService {
+	return &TaskService{Repo: repo, Now: now}
 }
 
-func (s *TaskService) GetTasks(search string) ([]models.Task, error) {
-	tasks := []models.Task{}
-	var rows *sql.Rows
-	var err error
-	if search == "" {
-		query := fmt.Sprintf("SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date LIMIT %d", tests.TaskLimit)
-		rows, err = s.DB.Query(query)
-	} else {
-		// проверка, является ли строка поиска датой
-		if i, dateErr := time.Parse("02.01.2006", search); dateErr == nil {
-			// преобразорвание даты в 20060102
-			formattedDate := i.Format(dates.DefaultDateFormat)
-			rows, err = s.DB.Query("SELECT id, date, title, comment, repeat FROM scheduler WHERE date = ? ORDER BY date LIMIT ?", formattedDate, tests.TaskLimit)
-		} else {
-			searchPattern := "%" + search + "%"
-			rows, err = s.DB.Query("SELECT id, date, title, comment, repeat FROM scheduler WHERE title LIKE ? OR comment LIKE ? ORDER BY date LIMIT ?", searchPattern, searchPattern, tests.TaskLimit)
-		}
-	}
+type TaskQuery struct { // параметры /api/tasks
+	Search  string // строка поиска, см. repository.Query
+	From    string // дата начала включительно: 02.01.2006, 20060102, today, tomorrow или yesterday
+	To      string // дата окончания включительно, в тех же форматах
+	Period  string // today, tomorrow, this week, overdue или next N days; пересекается с From и To
+	Sort    string // date, title, created, priority или time; при поиске по словам по умолчанию - по релевантности
+	Order   string // asc (по умолчанию) или desc
+	Cursor  string // next_cursor предыдущей страницы
+	Limit   int    // 0 - taskLimit
+	Parent  string // только подзадачи этой задачи
+	Blocked string // yes - только заблокированные задачи, no - без них
+}
 
+// GetTasks возвращает страницу задач и курсор следующей страницы (пусто - задач больше нет).
+// Ошибки параметров - *repository.QueryError и ErrInvalidFilter.
+func (s *TaskService) GetTasks(userID int64, params TaskQuery) ([]models.Task, string, error) {
+	now := s.Now()
+	query, err := repository.ParseQuery(params.Search, now)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
-			return nil, err
+	switch params.Blocked {
+	case "":
+	case "yes", "no": // то же, что условие blocked: в строке поиска
+		query = query.And(repository.Condition{Field: repository.FieldBlocked, Value: params.Blocked})
+	default:
+		return nil, "", fmt.Errorf("%w: blocked %q", ErrInvalidFilter, params.Blocked)
+	}
+	filter := repository.TaskFilter{Query: query, Limit: taskLimit}
+	if err := pageFilter(&filter, params); err != nil {
+		return nil, "", err
+	}
+	if filter.Parent, err = repository.ParseParentID(params.Parent); err != nil {
+		return nil, "", fmt.Errorf("%w: parent %q", ErrInvalidFilter, params.Parent)
+	}
+	if filter.From, filter.To, err = periodDates(params.Period, now); err != nil {
+		return nil, "", err
+	}
+	if params.From != "" {
+		from, err := repository.ParseDate(params.From, now)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: from %q", ErrInvalidFilter, params.From)
 		}
-		tasks = append(tasks, task)
+		filter.From = max(filter.From, from)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if params.To != "" {
+		to, err := repository.ParseDate(params.To, now)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: to %q", ErrInvalidFilter, params.To)
+		}
+		if filter.To == "" || to < filter.To {
+			filter.To = to
+		}
 	}
-	if tasks == nil {
-		tasks = []models.Task{}
+	if filter.From != "" && filter.To != "" && filter.From > filter.To { // пустой интервал
+		return []models.Task{}, "", nil
 	}
-	return tasks, nil
-}
 
-func (s *TaskService) GetTask(id int) (*models.Task, error) {
-	var task models.Task
-	err := s.DB.QueryRow("SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?", id).
-		Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	limit := filter.Limit
+	filter.Limit++ // лишняя задача показывает, что есть следующая страница
+	tasks, err := s.Repo.GetTasks(userID, filter)
+	if err != nil || len(tasks) <= limit {
+		return tasks, "", err
+	}
+	tasks = tasks[:limit]
+	var cursor *repository.Cursor
+	if filter.Sort == repository.SortRelevance { // ранги не сравнить между запросами, страница задается числом выданных задач
+		offset := limit
+		if filter.After != nil {
+			offset += filter.After.Offset
+		}
+		cursor, err = repository.NewRelevanceCursor(filter.Desc, offset, tasks[limit-1])
+	} else {
+		cursor, err = repository.NewCursor(filter.Sort, filter.Desc, tasks[limit-1])
+	}
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return &task, nil
+	return tasks, cursor.String(), nil
 }
 
-func (s *TaskService) UpdateTask(task models.Task) error {
-	result, err := s.DB.Exec("UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?", task.Date, task.Title, task.Comment, task.Repeat, task.ID)
-	if err != nil {
-		return err
+func pageFilter(filter *repository.TaskFilter, params TaskQuery) error { // сортировка, курсор и размер страницы
+	switch params.Sort {
+	case "":
+		filter.Sort = repository.SortDate
+		if filter.Query.HasText() {
+			filter.Sort = repository.SortRelevance
+		}
+	case repository.SortDate, repository.SortTitle, repository.SortCreated, repository.SortPriority, repository.SortTime:
+		filter.Sort = params.Sort
+	default:
+		return fmt.Errorf("%w: sort %q", ErrInvalidFilter, params.Sort)
+	}
+	switch params.Order {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return fmt.Errorf("%w: order %q", ErrInvalidFilter, params.Order)
+	}
+	if params.Limit < 0 || params.Limit > maxTaskLimit {
+		return fmt.Errorf("%w: limit must be from 1 to %d", ErrInvalidFilter, maxTaskLimit)
+	}
+	if params.Limit > 0 {
+		filter.Limit = params.Limit
 	}
-	rowsAffected, err := result.RowsAffected()
+	if params.Cursor == "" {
+		return nil
+	}
+	cursor, err := repository.ParseCursor(params.Cursor)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("task not found")
+	if (params.Sort != "" && params.Sort != cursor.Sort) || (params.Order != "" && filter.Desc != cursor.Desc) ||
+		(cursor.Sort == repository.SortRelevance && !filter.Query.HasText()) {
+		return fmt.Errorf("%w: cursor was issued for another sort order", ErrInvalidFilter)
 	}
+	filter.After, filter.Sort, filter.Desc = cursor, cursor.Sort, cursor.Desc
 	return nil
 }
 
-func (s *TaskService) DoneTask(id int, nextDate string) error {
-	if nextDate == "" {
-		_, err := s.DB.Exec("DELETE FROM scheduler WHERE id = ?", id)
+// periodDates - интервал дат для относительного периода. Неделя начинается с понедельника,
+// next N days включает сегодняшний день, overdue - все задачи до сегодняшнего дня.
+func periodDates(period string, now time.Time) (string, string, error) {
+	period = strings.Join(strings.Fields(strings.ToLower(period)), " ")
+	today := now.Format(dates.DefaultDateFormat)
+	switch period {
+	case "":
+		return "", "", nil
+	case "today":
+		return today, today, nil
+	case "tomorrow":
+		tomorrow := now.AddDate(0, 0, 1).Format(dates.DefaultDateFormat)
+		return tomorrow, tomorrow, nil
+	case "this week":
+		monday := now.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+		return monday.Format(dates.DefaultDateFormat), monday.AddDate(0, 0, 6).Format(dates.DefaultDateFormat), nil
+	case "overdue":
+		return "", now.AddDate(0, 0, -1).Format(dates.DefaultDateFormat), nil
+	}
+	var days int
+	if _, err := fmt.Sscanf(period, "next %d days", &days); err == nil && days >= 1 && days <= 366 &&
+		period == fmt.Sprintf("next %d days", days) {
+		return today, now.AddDate(0, 0, days-1).Format(dates.DefaultDateFormat), nil
+	}
+	return "", "", fmt.Errorf("%w: period %q", ErrInvalidFilter, period)
+}
+
+func (s *TaskService) GetTask(userID int64, id int) (*models.Task, error) {
+	return s.Repo.GetTask(userID, id)
+}
+
+func (s *TaskService) UpdateTask(userID int64, task models.Task) error {
+	if err := s.checkParent(userID, &task); err != nil {
 		return err
-	} else {
-		_, err := s.DB.Exec("UPDATE scheduler SET date = ? WHERE id = ?", nextDate, id)
+	}
+	if err := s.checkBlockers(userID, &task); err != nil {
 		return err
 	}
+	if task.ListID != "" { // перенос в другой список, пустой list_id оставляет задачу в ее списке
+		listID, err := repository.ParseListID(task.ListID)
+		if err != nil {
+			return err
+		}
+		if listID != 0 {
+			if err := s.requireListRole(userID, listID, models.RoleEditor); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Repo.UpdateTask(userID, task)
+}
+
+func (s *TaskService) TaskRole(userID int64, id int) (string, error) { // роль пользователя для задачи, у личной задачи владелец - ее автор
+	return s.Repo.TaskRole(userID, id)
 }
 
-func (s *TaskService) DeleteTask(id int) error {
-	result, err := s.DB.Exec("DELETE FROM scheduler WHERE id = ?", id)
+func (s *TaskService) requireListRole(userID int64, listID int64, role string) error {
+	current, err := s.Repo.ListRole(userID, listID)
 	if err != nil {
 		return err
 	}
-	rowsAffected, err := result.RowsAffected()
+	return requireRole(current, role)
+}
+
+// DoneTask выполняет задачу и записывает выполнение в историю с заметкой note.
+// repeat - правило для следующей даты (у RRULE с COUNT меняется), seriesDate - дата серии до переноса
+// на рабочий день, пустая nextDate удаляет задачу.
+func (s *TaskService) DoneTask(userID int64, id int, nextDate string, seriesDate string, repeat string, note string) error {
+	note, err := validation.NormalizeNote(note)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
+	task, err := s.Repo.GetTask(userID, id) // после удаления задачи в истории остается ее копия
+	if errors.Is(err, sql.ErrNoRows) {
 		return fmt.Errorf("task not found")
+	} else if err != nil {
+		return err
 	}
-	return nil
+	_, err = s.Repo.CompleteTask(userID, id, nextDate, seriesDate, repeat, models.Completion{
+		TaskID:      task.ID,
+		Title:       task.Title,
+		Date:        task.Date,
+		CompletedAt: s.Now().Format(time.RFC3339),
+		Note:        note,
+		ListID:      task.ListID,
+	})
+	return err
+}
+
+func (s *TaskService) SkipTask(userID int64, id int, nextDate string, seriesDate string, repeat string) error { // перенос на следующую дату без выполнения, количество повторений не меняется
+	if nextDate == "" {
+		return s.DeleteTask(userID, id)
+	}
+	return s.Repo.MoveTask(userID, id, nextDate, seriesDate, repeat, false)
+}
+
+func (s *TaskService) DeleteTask(userID int64, id int) error { // удаление задачи вместе с исключенными датами
+	return s.Repo.DeleteTask(userID, id)
 }
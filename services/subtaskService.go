@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/validation"
+)
+
+var (
+	ErrInvalidParent   = errors.New("invalid parent task")
+	ErrOpenSubtasks    = errors.New("the task has open subtasks")
+	ErrChecklistOrder  = errors.New("the order must contain every checklist item once")
+	ErrChecklistIsFull = errors.New("too many checklist items")
+)
+
+// checkParent проверяет родителя подзадачи: он доступен пользователю для изменения,
+// и задача не становится подзадачей самой себя. Подзадача всегда в списке родителя.
+func (s *TaskService) checkParent(userID int64, task *models.Task) error {
+	parentID, err := repository.ParseParentID(task.ParentID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidParent, err)
+	}
+	if parentID == 0 {
+		task.ParentID = ""
+		return nil
+	}
+	role, err := s.Repo.TaskRole(userID, int(parentID))
+	if err != nil {
+		if err.Error() == "task not found" {
+			return fmt.Errorf("%w: parent task not found", ErrInvalidParent)
+		}
+		return err
+	}
+	if models.RoleRank(role) < models.RoleRank(models.RoleEditor) {
+		return ErrForbidden
+	}
+
+	parent, err := s.Repo.GetTask(userID, int(parentID))
+	if err != nil {
+		return err
+	}
+	for ancestor, seen := parent, map[string]bool{}; ; { // цепочка родителей не должна приводить к самой задаче
+		if ancestor.ID == task.ID || seen[ancestor.ID] {
+			return fmt.Errorf("%w: a task cannot be a subtask of itself", ErrInvalidParent)
+		}
+		seen[ancestor.ID] = true
+		id, _ := strconv.Atoi(ancestor.ParentID)
+		if id == 0 {
+			break
+		}
+		if ancestor, err = s.Repo.GetTask(userID, id); err != nil {
+			break // недоступный предок задачу уже не касается
+		}
+	}
+
+	listID := parent.ListID
+	if listID == "" {
+		listID = "0" // непустой list_id переносит задачу, "0" - в личные задачи
+	}
+	if current, _ := repository.ParseListID(task.ListID); task.ListID != "" && strconv.FormatInt(current, 10) != listID {
+		return fmt.Errorf("%w: a subtask must be in the list of its parent task", ErrInvalidParent)
+	}
+	task.ParentID = parent.ID
+	task.ListID = listID
+	return nil
+}
+
+// OpenSubtasks - подзадачи, которые еще не выполнены к дате задачи: разовые,
+// пока существуют, и повторяющиеся, пока их дата не позже даты родителя.
+func (s *TaskService) OpenSubtasks(userID int64, task *models.Task) ([]models.Task, error) {
+	parentID, err := strconv.ParseInt(task.ID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := s.Repo.GetTasks(userID, repository.TaskFilter{Parent: parentID})
+	if err != nil {
+		return nil, err
+	}
+	open := subtasks[:0]
+	for _, subtask := range subtasks {
+		if subtask.Repeat == "" || subtask.Date <= task.Date {
+			open = append(open, subtask)
+		}
+	}
+	return open, nil
+}
+
+func (s *TaskService) AddChecklistItem(userID int64, taskID int, text string) (int64, error) {
+	text, err := validation.NormalizeChecklistText(text)
+	if err != nil {
+		return 0, err
+	}
+	task, err := s.Repo.GetTask(userID, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if len(task.Checklist) >= models.MaxChecklistItems {
+		return 0, ErrChecklistIsFull
+	}
+	return s.Repo.AddChecklistItem(userID, taskID, text)
+}
+
+// UpdateChecklistItem меняет текст и/или отметку пункта, nil - оставить как есть.
+func (s *TaskService) UpdateChecklistItem(userID int64, taskID int, id int64, text *string, done *bool) error {
+	task, err := s.Repo.GetTask(userID, taskID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(task.Checklist, func(item models.ChecklistItem) bool { return item.ID == id })
+	if i < 0 {
+		return repository.ErrItemNotFound
+	}
+	item := task.Checklist[i]
+	if text != nil {
+		if item.Text, err = validation.NormalizeChecklistText(*text); err != nil {
+			return err
+		}
+	}
+	if done != nil {
+		item.Done = *done
+	}
+	return s.Repo.UpdateChecklistItem(userID, taskID, item)
+}
+
+func (s *TaskService) ReorderChecklist(userID int64, taskID int, ids []int64) error { // ids - все пункты в новом порядке
+	task, err := s.Repo.GetTask(userID, taskID)
+	if err != nil {
+		return err
+	}
+	current := make([]int64, len(task.Checklist))
+	for i, item := range task.Checklist {
+		current[i] = item.ID
+	}
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	slices.Sort(current)
+	if !slices.Equal(sorted, current) {
+		return ErrChecklistOrder
+	}
+	return s.Repo.ReorderChecklist(userID, taskID, ids)
+}
+
+func (s *TaskService) DeleteChecklistItem(userID int64, taskID int, id int64) error {
+	return s.Repo.DeleteChecklistItem(userID, taskID, id)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rust2014/go_final_project/auth"
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+)
+
+const maxTokenNameLength = 128
+
+type TokenService struct { // личные API-токены для скриптов и интеграций
+	DB *database.DB
+}
+
+func NewTokenService(db *database.DB) *TokenService {
+	return &TokenService{DB: db}
+}
+
+func tokenHash(token string) string { // токены случайные и длинные, соль не нужна
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *TokenService) CreateToken(userID int64, name string, readOnly bool) (*models.APIToken, error) { // в ответе единственный раз возвращается сам токен
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxTokenNameLength {
+		return nil, errors.New("token name is too long")
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	token := auth.APITokenPrefix + hex.EncodeToString(random)
+	created := time.Now().UTC().Format(time.RFC3339)
+
+	readOnlyFlag := 0 // read_only хранится числом 0/1 в обоих диалектах
+	if readOnly {
+		readOnlyFlag = 1
+	}
+	var id int64
+	err := s.DB.QueryRow("INSERT INTO api_tokens (user_id, name, token_hash, read_only, created) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		userID, name, tokenHash(token), readOnlyFlag, created).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.APIToken{ID: id, Name: name, ReadOnly: readOnly, Created: created, Token: token}, nil
+}
+
+func (s *TokenService) GetTokens(userID int64) ([]models.APIToken, error) {
+	rows, err := s.DB.Query("SELECT id, name, read_only, created, last_used FROM api_tokens WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var token models.APIToken
+		if err := rows.Scan(&token.ID, &token.Name, &token.ReadOnly, &token.Created, &token.LastUsed); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *TokenService) RevokeToken(userID int64, id int64) error {
+	result, err := s.DB.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("token not found")
+	}
+	return nil
+}
+
+func (s *TokenService) Authenticate(token string) (int64, bool, error) { // владелец токена и признак "только чтение" (auth.Tokens)
+	var id, userID int64
+	var readOnly bool
+	err := s.DB.QueryRow("SELECT id, user_id, read_only FROM api_tokens WHERE token_hash = ?", tokenHash(token)).Scan(&id, &userID, &readOnly)
+	if err == sql.ErrNoRows {
+		return 0, false, auth.ErrInvalidToken
+	} else if err != nil {
+		return 0, false, err
+	}
+	_, err = s.DB.Exec("UPDATE api_tokens SET last_used = ? WHERE id = ?", time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, readOnly, nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserExists       = errors.New("user already exists")
+	ErrWrongCredentials = errors.New("wrong login or password")
+	ErrInvalidLogin     = errors.New("invalid login")
+	ErrInvalidPassword  = errors.New("password must be 6 to 72 bytes long")
+)
+
+const (
+	minPasswordLength = 6
+	maxLoginLength    = 64
+	maxPasswordLength = 72 // bcrypt учитывает только первые 72 байта
+)
+
+type UserService struct { // зарегистрированные пользователи многопользовательского режима
+	DB *database.DB
+}
+
+func NewUserService(db *database.DB) *UserService {
+	return &UserService{DB: db}
+}
+
+func (s *UserService) Register(login string, password string) (*models.User, error) {
+	login = strings.TrimSpace(login)
+	if login == "" || utf8.RuneCountInString(login) > maxLoginLength || strings.ContainsAny(login, " \t\n") {
+		return nil, ErrInvalidLogin
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow("SELECT COUNT(*) FROM users WHERE login = ?", login).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, ErrUserExists
+	}
+	var id int64
+	err = tx.QueryRow("INSERT INTO users (login, password_hash) VALUES (?, ?) RETURNING id", login, string(hash)).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &models.User{ID: id, Login: login, PasswordHash: string(hash)}, nil
+}
+
+func (s *UserService) Authenticate(login string, password string) (*models.User, error) { // проверка логина и пароля
+	var user models.User
+	err := s.DB.QueryRow("SELECT id, login, password_hash FROM users WHERE login = ?", strings.TrimSpace(login)).
+		Scan(&user.ID, &user.Login, &user.PasswordHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrWrongCredentials
+	} else if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrWrongCredentials
+	}
+	return &user, nil
+}
+
+func (s *UserService) Secret(userID int64) (string, error) { // хэш пароля, которым подписан токен пользователя (auth.Users)
+	var hash string
+	err := s.DB.QueryRow("SELECT password_hash FROM users WHERE id = ?", userID).Scan(&hash)
+	return hash, err
+}
+
+// SigningKey - ключ подписи токенов из таблицы settings; при первом запуске создается случайный.
+func (s *UserService) SigningKey() ([]byte, error) {
+	var value string
+	err := s.DB.QueryRow("SELECT value FROM settings WHERE name = 'jwt_key'").Scan(&value)
+	if err == sql.ErrNoRows {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		// при одновременном запуске остается ключ, сохраненный первым
+		if _, err := s.DB.Exec("INSERT INTO settings (name, value) VALUES ('jwt_key', ?) ON CONFLICT (name) DO NOTHING", hex.EncodeToString(key)); err != nil {
+			return nil, err
+		}
+		err = s.DB.QueryRow("SELECT value FROM settings WHERE name = 'jwt_key'").Scan(&value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(value)
+}
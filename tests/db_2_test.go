@@ -6,8 +6,8 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite" // тот же драйвер, что у сервера: в нем есть FTS5 для триггеров поиска
 )
 
 type Task struct {
@@ -16,6 +16,17 @@ type Task struct {
 	Title   string `db:"title"`
 	Comment string `db:"comment"`
 	Repeat  string `db:"repeat"`
+
+	RepeatUntil string `db:"repeat_until"`
+	RepeatCount int64  `db:"repeat_count"`
+	RepeatMode  string `db:"repeat_mode"`
+	UserID      int64  `db:"user_id"`
+	ListID      int64  `db:"list_id"`
+	Priority    int64  `db:"priority"`
+	StartTime   string `db:"start_time"`
+	Duration    int64  `db:"duration"`
+	ParentID    int64  `db:"parent_id"`
+	SeriesDate  string `db:"series_date"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -29,7 +40,7 @@ func openDB(t *testing.T) *sqlx.DB {
 	if len(envFile) > 0 {
 		dbfile = envFile
 	}
-	db, err := sqlx.Connect("sqlite3", dbfile)
+	db, err := sqlx.Connect("sqlite", dbfile)
 	assert.NoError(t, err)
 	return db
 }
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	forEachRepository(t, testDependencies)
+}
+
+func testDependencies(t *testing.T, _ *database.DB, repo repository.TaskRepository) {
+	service := services.NewTaskService(repo, time.Now)
+	today := time.Now().Format(`20060102`)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	nextWeek := time.Now().AddDate(0, 0, 7).Format(`20060102`)
+
+	add := func(task models.Task) string {
+		id, err := service.AddTask(1, task)
+		assert.NoError(t, err, task.Title)
+		return fmt.Sprint(id)
+	}
+	order := add(models.Task{Date: today, Title: "Заказать деталь"})
+	install := add(models.Task{Date: tomorrow, Title: "Установить деталь", BlockedBy: []string{order, order}})
+	check := add(models.Task{Date: tomorrow, Title: "Проверить", BlockedBy: []string{install}})
+	weekly := add(models.Task{Date: nextWeek, Title: "Уборка", Repeat: "d 7"})
+	cleaned := add(models.Task{Date: tomorrow, Title: "Пылесос", BlockedBy: []string{weekly}})
+
+	get := func(id string) *models.Task {
+		n, _ := strconv.Atoi(id)
+		task, err := service.GetTask(1, n)
+		assert.NoError(t, err, id)
+		return task
+	}
+	if task := get(install); assert.NotNil(t, task) {
+		assert.Equal(t, []string{order}, task.BlockedBy)
+		assert.True(t, task.Blocked)
+	}
+	assert.True(t, get(check).Blocked)
+	assert.False(t, get(order).Blocked)
+	assert.False(t, get(cleaned).Blocked) // повторяющаяся блокирующая задача еще не наступила
+
+	ids := func(params services.TaskQuery) []string {
+		tasks, _, err := service.GetTasks(1, params)
+		assert.NoError(t, err, params)
+		result := []string{}
+		for _, task := range tasks {
+			result = append(result, task.ID)
+		}
+		return result
+	}
+	assert.Equal(t, []string{order, weekly, cleaned}, ids(services.TaskQuery{Blocked: "no", Sort: "created"}))
+	assert.Equal(t, []string{install, check}, ids(services.TaskQuery{Blocked: "yes", Sort: "created"}))
+	assert.Equal(t, []string{install, check}, ids(services.TaskQuery{Search: "blocked:yes", Sort: "created"}))
+	assert.Equal(t, []string{install, check}, ids(services.TaskQuery{Search: "проверить OR установить OR заказать", Blocked: "yes", Sort: "created"}))
+	_, _, err := service.GetTasks(1, services.TaskQuery{Blocked: "maybe"})
+	assert.ErrorIs(t, err, services.ErrInvalidFilter)
+
+	// циклы, сама задача и недоступные задачи запрещены
+	task := get(order)
+	for _, blockedBy := range [][]string{{check}, {install}, {order}, {"100500"}, {"abc"}} {
+		task.BlockedBy = blockedBy
+		assert.ErrorIs(t, service.UpdateTask(1, *task), services.ErrInvalidDependency, blockedBy)
+	}
+	_, err = service.AddTask(2, models.Task{Date: today, Title: "Чужая", BlockedBy: []string{order}})
+	assert.ErrorIs(t, err, services.ErrInvalidDependency)
+
+	blockers, err := service.OpenBlockers(1, get(check))
+	if assert.NoError(t, err) && assert.Len(t, blockers, 1) {
+		assert.Equal(t, install, blockers[0].ID)
+	}
+
+	// выполненная разовая задача удаляется и больше не блокирует
+	n, _ := strconv.Atoi(order)
+	assert.NoError(t, service.DoneTask(1, n, "", "", "", ""))
+	if task := get(install); assert.NotNil(t, task) {
+		assert.Empty(t, task.BlockedBy)
+		assert.False(t, task.Blocked)
+	}
+	assert.True(t, get(check).Blocked)
+
+	// разовая блокирующая задача блокирует, даже если ее дата позже
+	pack := add(models.Task{Date: nextWeek, Title: "Упаковать"})
+	ship := add(models.Task{Date: today, Title: "Отправить", BlockedBy: []string{pack}})
+	assert.True(t, get(ship).Blocked)
+	assert.Equal(t, []string{check, ship}, ids(services.TaskQuery{Blocked: "yes", Sort: "created"}))
+	blockers, err = service.OpenBlockers(1, get(ship))
+	if assert.NoError(t, err) && assert.Len(t, blockers, 1) {
+		assert.Equal(t, pack, blockers[0].ID)
+	}
+}
+
+func TestDependenciesAPI(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Заказать"})
+	assert.Equal(t, http.StatusOK, code)
+	order := fmt.Sprint(m["id"])
+	code, m = serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Установить", "blocked_by": []string{order}})
+	assert.Equal(t, http.StatusOK, code)
+	install := fmt.Sprint(m["id"])
+
+	code, m = serve(t, router, http.MethodGet, "api/task?id="+install, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, m["blocked"])
+	assert.Equal(t, []any{order}, m["blocked_by"])
+
+	code, m = serve(t, router, http.MethodGet, "api/tasks?blocked=no", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+	code, _ = serve(t, router, http.MethodGet, "api/tasks?blocked=1", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, m = serve(t, router, http.MethodPut, "api/task", map[string]any{"id": order, "date": today, "title": "Заказать", "blocked_by": []string{install}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, m["error"])
+
+	// изменение из веб-интерфейса без blocked_by не снимает зависимости, пустой список снимает
+	task := map[string]any{"id": install, "date": today, "title": "Установить и настроить", "comment": "", "repeat": ""}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+install, nil)
+	assert.Equal(t, []any{order}, m["blocked_by"])
+	task["blocked_by"] = []string{}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+install, nil)
+	assert.NotContains(t, m, "blocked_by")
+	task["blocked_by"] = []string{order}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+
+	// выполнить заблокированную задачу можно, но ответ предупреждает об этом
+	code, m = serve(t, router, http.MethodPost, "api/task/done?id="+install, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, m["warning"])
+	assert.Equal(t, []any{order}, m["blocked_by"])
+	code, m = serve(t, router, http.MethodPost, "api/task/done?id="+order, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, m, "warning")
+}
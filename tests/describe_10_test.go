@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		lang   string
+		want   string
+	}{
+		{"d 1", "ru", "каждый день"},
+		{"d 3", "en", "every 3 days"},
+		{"w 1,3,5", "ru", "по понедельникам, средам и пятницам"},
+		{"w 1,3,5", "en", "every Monday, Wednesday and Friday"},
+		{"m -1,15 3,6", "en", "on the 15th and the last day of March and June"},
+		{"m -1,15 3,6", "ru", "15-го числа и в последний день марта и июня"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=2TU", "en", "every month on the second Tuesday"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=2TU", "ru", "каждый месяц во второй вторник"},
+		{"k 34", "ru", ""},
+	}
+	for _, v := range tbl {
+		body, err := requestJSON("api/describe?lang="+v.lang+"&repeat="+url.QueryEscape(v.repeat), nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]string
+		assert.NoError(t, json.Unmarshal(body, &m))
+		if v.want == "" {
+			assert.NotEmpty(t, m["error"], "Ожидается ошибка для %q", v.repeat)
+			continue
+		}
+		assert.Equal(t, v.want, m["repeat_text"])
+	}
+
+	id := addTask(t, task{
+		date:   time.Now().Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "w 1,3,5",
+	})
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "по понедельникам, средам и пятницам", m["repeat_text"])
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExdates(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	m, err := postJSON("api/task", map[string]any{
+		"date":    day(0),
+		"title":   "Без правила",
+		"exdates": []string{day(1)},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":    day(0),
+		"title":   "Пробежка",
+		"repeat":  "d 1",
+		"exdates": []string{day(2), day(1), day(2)},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, []any{day(1), day(2)}, got["exdates"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, day(3), task.Date)
+
+	var left int
+	assert.NoError(t, db.Get(&left, `SELECT count(*) FROM exdates WHERE task_id=?`, id))
+	assert.Equal(t, 0, left)
+
+	_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+}
+
+func TestExdatesUpdate(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	day := func(n int) string { return time.Now().AddDate(0, 0, n).Format(`20060102`) }
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{
+		"date": day(0), "title": "Пробежка", "repeat": "d 1", "exdates": []string{day(1)},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	// изменение из веб-интерфейса без exdates не трогает исключенные даты
+	task := map[string]any{"id": id, "date": day(0), "title": "Пробежка в парке", "comment": "", "repeat": "d 1"}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, []any{day(1)}, m["exdates"])
+
+	task["exdates"] = []string{}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.NotContains(t, m, "exdates")
+
+	// без правила повторения исключенные даты не сохраняются
+	task["exdates"] = []string{day(1)}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+	delete(task, "exdates")
+	task["repeat"] = ""
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.NotContains(t, m, "exdates")
+}
+
+func TestSkipTask(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	ret, err := postJSON("api/task", map[string]any{
+		"date":         now.Format(`20060102`),
+		"title":        "Массаж",
+		"repeat":       "d 2",
+		"repeat_count": 2,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task/skip?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var skipped Task
+	err = db.Get(&skipped, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), skipped.Date)
+	assert.Equal(t, int64(2), skipped.RepeatCount)
+
+	once := addTask(t, task{date: now.Format(`20060102`), title: "Разовая"})
+	ret, err = postJSON("api/task/skip?id="+once, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	ret, err = postJSON("api/task/skip?id=wjhgese", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskFields(t *testing.T) {
+	forEachRepository(t, testTaskFields)
+}
+
+func testTaskFields(t *testing.T, _ *database.DB, repo repository.TaskRepository) {
+	service := services.NewTaskService(repo, time.Now)
+	today := time.Now().Format(`20060102`)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	ids := []string{""} // номер задачи -> id, задачи с единицы
+	for _, task := range []models.Task{
+		{Date: tomorrow, Title: "Отчет", Priority: 4, Tags: []string{"#Работа", "срочно", "работа"}, Time: "09:30", Duration: 90},
+		{Date: today, Title: "Хлеб", Priority: 1, Tags: []string{"дом"}},
+		{Date: tomorrow, Title: "Звонок", Priority: 2, Tags: []string{"работа"}, Time: "08:00"},
+		{Date: tomorrow, Title: "Уборка"},
+	} {
+		id, err := service.AddTask(1, task)
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(id))
+	}
+
+	first, err := strconv.Atoi(ids[1])
+	assert.NoError(t, err)
+	task, err := service.GetTask(1, first)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, task.Priority)
+		assert.Equal(t, []string{"работа", "срочно"}, task.Tags)
+		assert.Equal(t, "09:30", task.Time)
+		assert.Equal(t, 90, task.Duration)
+	}
+
+	// метки заменяются целиком, остальные поля сохраняются
+	task.Tags, task.Priority = []string{"архив"}, 3
+	assert.NoError(t, repo.UpdateTask(1, *task))
+	task, err = service.GetTask(1, first)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"архив"}, task.Tags)
+		assert.Equal(t, 3, task.Priority)
+		assert.Equal(t, "09:30", task.Time)
+	}
+
+	tbl := []struct {
+		params services.TaskQuery
+		want   []int
+	}{
+		{services.TaskQuery{Search: "tag:работа"}, []int{3}},
+		{services.TaskQuery{Search: "tag:#Архив OR tag:дом"}, []int{2, 1}},
+		{services.TaskQuery{Search: "-tag:дом priority:3"}, []int{1}},
+		{services.TaskQuery{Sort: "priority", Order: "desc"}, []int{1, 3, 2, 4}},
+		{services.TaskQuery{Sort: "time"}, []int{2, 4, 3, 1}},
+	}
+	for _, v := range tbl {
+		var got []string
+		cursor := ""
+		for { // по две задачи на странице, чтобы проверить курсор
+			params := v.params
+			params.Cursor, params.Limit = cursor, 2
+			if cursor != "" {
+				params.Sort, params.Order = "", ""
+			}
+			tasks, next, err := service.GetTasks(1, params)
+			if !assert.NoError(t, err, v.params) {
+				break
+			}
+			for _, task := range tasks {
+				got = append(got, task.ID)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		want := make([]string, len(v.want))
+		for i, n := range v.want {
+			want[i] = ids[n]
+		}
+		assert.Equal(t, want, got, v.params)
+	}
+
+	for _, task := range []models.Task{
+		{Title: "Приоритет", Priority: 5},
+		{Title: "Метка", Tags: []string{"две метки"}},
+		{Title: "Пустая метка", Tags: []string{"#"}},
+		{Title: "Время", Time: "25:00"},
+		{Title: "Время", Time: "9:30"},
+		{Title: "Длительность", Duration: 30},
+		{Title: "Длительность", Time: "10:00", Duration: 1441},
+	} {
+		_, err := service.AddTask(1, task)
+		assert.Error(t, err, task)
+	}
+}
+
+func TestTaskFieldsAPI(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Встреча",
+		"priority": 2, "tags": []string{"Работа"}, "time": "14:00", "duration": 60})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	code, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), m["priority"])
+	assert.Equal(t, []any{"работа"}, m["tags"])
+	assert.Equal(t, "14:00", m["time"])
+	assert.Equal(t, float64(60), m["duration"])
+
+	code, m = serve(t, router, http.MethodGet, "api/tasks?search=tag:работа", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+
+	// веб-интерфейс отправляет только основные поля, остальные не меняются
+	task := map[string]any{"id": id, "date": today, "title": "Встреча в 14", "comment": "", "repeat": ""}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+	code, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Встреча в 14", m["title"])
+	assert.Equal(t, float64(2), m["priority"])
+	assert.Equal(t, []any{"работа"}, m["tags"])
+	assert.Equal(t, "14:00", m["time"])
+	assert.Equal(t, float64(60), m["duration"])
+
+	task = map[string]any{"id": id, "date": today, "title": "Встреча", "priority": 7}
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusBadRequest, code)
+	task["priority"], task["time"], task["duration"] = 1, "", 30
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusBadRequest, code)
+	task["tags"] = []string{}
+	delete(task, "duration")
+	code, _ = serve(t, router, http.MethodPut, "api/task", task)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), m["priority"])
+	assert.NotContains(t, m, "tags")
+	assert.NotContains(t, m, "time")
+	assert.NotContains(t, m, "duration")
+}
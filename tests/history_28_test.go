@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	forEachRepository(t, testHistory)
+}
+
+func testHistory(t *testing.T, _ *database.DB, repo repository.TaskRepository) {
+	now := time.Now()
+	service := services.NewTaskService(repo, func() time.Time { return now })
+	today := now.Format(`20060102`)
+	nextWeek := now.AddDate(0, 0, 7).Format(`20060102`)
+
+	add := func(task models.Task) int {
+		id, err := service.AddTask(1, task)
+		assert.NoError(t, err, task.Title)
+		return int(id)
+	}
+	filter := add(models.Task{Date: today, Title: "Замена фильтра", Repeat: "d 7"})
+	call := add(models.Task{Date: today, Title: "Звонок"})
+
+	assert.NoError(t, service.DoneTask(1, filter, nextWeek, "", "d 7", "Фильтр A-12"))
+	assert.NoError(t, service.DoneTask(1, filter, now.AddDate(0, 0, 14).Format(`20060102`), "", "d 7", ""))
+	assert.NoError(t, service.DoneTask(1, call, "", "", "", ""))
+
+	history, err := service.TaskHistory(1, filter)
+	if assert.NoError(t, err) && assert.Len(t, history, 2) {
+		assert.Equal(t, nextWeek, history[0].Date) // новые первыми
+		assert.Empty(t, history[0].Note)
+		assert.Equal(t, today, history[1].Date)
+		assert.Equal(t, "Фильтр A-12", history[1].Note)
+		assert.Equal(t, "Замена фильтра", history[1].Title)
+		assert.Equal(t, strconv.Itoa(filter), history[1].TaskID)
+		assert.Equal(t, now.Format(time.RFC3339), history[1].CompletedAt)
+	}
+
+	// разовая задача удалена, но ее выполнение осталось
+	_, err = service.GetTask(1, call)
+	assert.Error(t, err)
+	history, err = service.TaskHistory(1, call)
+	if assert.NoError(t, err) && assert.Len(t, history, 1) {
+		assert.Equal(t, "Звонок", history[0].Title)
+	}
+	history, err = service.TaskHistory(1, 100500)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Empty(t, history)
+	_, err = service.TaskHistory(2, filter)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.Error(t, service.DoneTask(1, filter, "", "", "", strings.Repeat("a", models.MaxNoteLen+1)))
+
+	// лента по страницам
+	var got []string
+	cursor := ""
+	for {
+		page, next, err := service.History(1, cursor, 2)
+		if !assert.NoError(t, err) {
+			break
+		}
+		for _, completion := range page {
+			got = append(got, completion.Title)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, []string{"Звонок", "Замена фильтра", "Замена фильтра"}, got)
+
+	history, _, err = service.History(2, "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+	for _, cursor := range []string{"abc", "0"} {
+		_, _, err = service.History(1, cursor, 0)
+		assert.ErrorIs(t, err, services.ErrInvalidFilter, cursor)
+	}
+	_, _, err = service.History(1, "", 1000)
+	assert.ErrorIs(t, err, services.ErrInvalidFilter)
+}
+
+func TestHistoryTransaction(t *testing.T) {
+	t.Setenv("TODO_DBFILE", filepath.Join(t.TempDir(), "scheduler.db"))
+	testSQLRepository(t, testHistoryTransaction)
+}
+
+func testHistoryTransaction(t *testing.T, db *database.DB, repo repository.TaskRepository) {
+	service := services.NewTaskService(repo, time.Now)
+	today := time.Now().Format(`20060102`)
+	id, err := service.AddTask(1, models.Task{Date: today, Title: "Звонок"})
+	assert.NoError(t, err)
+
+	// выполнение не записалось - задача остается на месте
+	_, err = db.Exec("DROP TABLE history")
+	assert.NoError(t, err)
+	assert.Error(t, service.DoneTask(1, int(id), "", "", "", ""))
+	task, err := service.GetTask(1, int(id))
+	if assert.NoError(t, err) {
+		assert.Equal(t, today, task.Date)
+	}
+}
+
+func TestHistoryAPI(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Полив", "repeat": "d 1"})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+id, map[string]any{"note": "Все цветы"})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+id+"&note=Только+кактус", nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, m = serve(t, router, http.MethodGet, "api/task/history?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	history, _ := m["history"].([]any)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, "Только кактус", history[0].(map[string]any)["note"])
+		assert.Equal(t, today, history[1].(map[string]any)["date"])
+	}
+	code, _ = serve(t, router, http.MethodGet, "api/task/history?id=100500", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, router, http.MethodGet, "api/task/history?id=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, m = serve(t, router, http.MethodGet, "api/history?limit=1", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["history"], 1)
+	if assert.NotEmpty(t, m["next_cursor"]) {
+		code, m = serve(t, router, http.MethodGet, "api/history?cursor="+fmt.Sprint(m["next_cursor"]), nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, m["history"], 1)
+		assert.NotContains(t, m, "next_cursor")
+	}
+	code, _ = serve(t, router, http.MethodGet, "api/history?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(t, router, http.MethodGet, "api/history?cursor=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/dates"
+	"github.com/rust2014/go_final_project/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHolidays(t *testing.T) {
+	ret, err := postJSON("api/holidays", map[string]any{"date": "20240129", "name": "Выходной"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/holidays", map[string]any{"date": "29.01.2024"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	code, ret := serve(t, nil, http.MethodPost, "api/holidays/import?format=csv", "date,name\n2024-03-08,Международный женский день\n")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), ret["imported"])
+
+	code, ret = serve(t, nil, http.MethodPost, "api/holidays/import?format=ics", strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20240501",
+		"DTEND;VALUE=DATE:20240503",
+		"SUMMARY:Праздник",
+		"  весны",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), ret["imported"])
+
+	body, err := requestJSON("api/holidays", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list map[string][]map[string]string
+	assert.NoError(t, json.Unmarshal(body, &list))
+	assert.Contains(t, list["holidays"], map[string]string{"date": "20240502", "name": "Праздник весны"})
+
+	tbl := []nextDate{
+		{"20240126", "d 3 +wd", "20240130"},
+		{"20240126", "d 1 workdays", "20240130"},
+		{"20240126", "w 6 +wd", "20240130"},
+		{"20240126", "m 28 -wd", "20240228"},
+		{"20240126", "m 8 3 -wd", "20240307"},
+		{"20240126", "RRULE:FREQ=YEARLY;BYMONTH=5;BYMONTHDAY=1 +wd", "20240503"},
+		{"20240126", "d 3 +wd +wd", ""},
+		{"20240126", "+wd", ""},
+	}
+	for _, v := range tbl {
+		get, err := getBody(fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s", v.date, url.QueryEscape(v.repeat)))
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		if len(v.want) == 0 {
+			assert.NotRegexp(t, `^\d{8}$`, next, "Ожидается ошибка для %q", v.repeat)
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`, v.date, v.repeat, v.want)
+	}
+
+	for _, date := range []string{"20240129", "20240308", "20240501", "20240502"} {
+		ret, err = postJSON("api/holidays?date="+date, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	ret, err = postJSON("api/holidays?date=20240129", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
+
+// TestWorkdaySeries проверяет, что перенос на рабочий день не сдвигает следующие даты серии.
+func TestWorkdaySeries(t *testing.T) {
+	now := time.Date(2027, 1, 1, 12, 0, 0, 0, time.Local)
+	tbl := []struct {
+		repeat string
+		want   []string
+	}{
+		{"y +wd", []string{"20280103", "20290102", "20300102", "20310102", "20320102", "20330103"}},
+		{"RRULE:FREQ=MONTHLY +wd", []string{"20270202", "20270302", "20270402", "20270503", "20270602", "20270702"}},
+		{"d 7 -wd", []string{"20270108", "20270115", "20270122", "20270129", "20270205", "20270212"}},
+	}
+	for _, v := range tbl {
+		occurrences, err := dates.Occurrences(now, "20270102", v.repeat, len(v.want), "", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, occurrences, v.repeat)
+	}
+
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	s := memoryServices(t)
+	s.Tasks.Now = func() time.Time { return now }
+	router := server.NewRouter(s, "../web")
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": "20270102", "title": "Годовой отчет", "repeat": "y +wd"})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+	for i, want := range tbl[0].want[:5] {
+		code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+id, nil)
+		assert.Equal(t, http.StatusOK, code)
+		code, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, want, m["date"])
+		if i == 0 { // изменение без новой даты и правила сохраняет дату серии
+			code, _ = serve(t, router, http.MethodPut, "api/task", map[string]any{"id": id, "date": want, "title": "Отчет за год", "comment": "", "repeat": "y +wd"})
+			assert.Equal(t, http.StatusOK, code)
+		}
+	}
+}
+
+func TestHolidaysAdminOnly(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "admin-password")
+	t.Setenv("TODO_MULTIUSER", "1")
+	t.Setenv("TODO_JWT_SECRET", "memory")
+	router := memoryRouter(t)
+
+	code, m := serve(t, router, http.MethodPost, "api/signup", map[string]any{"login": "alice", "password": "secret1"})
+	assert.Equal(t, http.StatusOK, code)
+	user, _ := m["token"].(string)
+	code, m = serve(t, router, http.MethodPost, "api/signin", map[string]any{"password": "admin-password"})
+	assert.Equal(t, http.StatusOK, code)
+	admin, _ := m["token"].(string)
+
+	// праздники общие: пользователь их только читает
+	holiday := map[string]any{"date": "20990101", "name": "Новый год"}
+	code, _ = serve(t, router, http.MethodGet, "api/holidays", nil, withCookie(user))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodPost, "api/holidays", holiday, withCookie(user))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve(t, router, http.MethodPost, "api/holidays/import?format=csv", nil, withCookie(user))
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = serve(t, router, http.MethodPost, "api/holidays", holiday, withCookie(admin))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodDelete, "api/holidays?date=20990101", nil, withCookie(user))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve(t, router, http.MethodDelete, "api/holidays?date=20990101", nil, withCookie(admin))
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLists(t *testing.T) {
+	owner := ""
+	if password := os.Getenv("TODO_PASSWORD"); password != "" {
+		m, err := postJSON("api/signin", map[string]any{"password": password}, http.MethodPost)
+		assert.NoError(t, err)
+		owner, _ = m["token"].(string)
+	}
+	today := time.Now().Format(`20060102`)
+
+	code, m := serve(t, nil, http.MethodPost, "api/lists", map[string]any{"name": "Дом"}, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	listID := fmt.Sprint(m["id"])
+
+	code, m = serve(t, nil, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Купить продукты", "list_id": listID}, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	code, m = serve(t, nil, http.MethodGet, "api/task?id="+id, nil, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, listID, m["list_id"])
+
+	code, _ = serve(t, nil, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Чужой список", "list_id": "999999"}, withCookie(owner))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// PUT без list_id оставляет задачу в ее списке
+	code, _ = serve(t, nil, http.MethodPut, "api/task", map[string]any{"id": id, "date": today, "title": "Купить хлеб"}, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	code, m = serve(t, nil, http.MethodGet, "api/task?id="+id, nil, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, listID, m["list_id"])
+
+	code, m = serve(t, nil, http.MethodGet, "api/lists", nil, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	found := false
+	for _, item := range m["lists"].([]any) {
+		list := item.(map[string]any)
+		if fmt.Sprint(list["id"]) == listID {
+			found = true
+			assert.Equal(t, "owner", list["role"])
+		}
+	}
+	assert.True(t, found)
+
+	switch os.Getenv("TODO_MULTIUSER") {
+	case "1", "true", "yes", "on":
+		testSharedList(t, id)
+	}
+
+	code, _ = serve(t, nil, http.MethodDelete, "api/lists?id="+listID, nil, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, nil, http.MethodGet, "api/task?id="+id, nil, withCookie(owner))
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func testSharedList(t *testing.T, foreignTaskID string) { // foreignTaskID - задача в списке другого пользователя
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	alice := signUp(t, nil, "alice"+suffix)
+	bob := signUp(t, nil, "bob"+suffix)
+	carol := signUp(t, nil, "carol"+suffix)
+	today := time.Now().Format(`20060102`)
+
+	code, _ := serve(t, nil, http.MethodGet, "api/task?id="+foreignTaskID, nil, withCookie(alice))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, m := serve(t, nil, http.MethodPost, "api/lists", map[string]any{"name": "Команда"}, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+	listID := fmt.Sprint(m["id"])
+	code, m = serve(t, nil, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Дежурство", "repeat": "d 7", "list_id": listID}, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	var list int64
+	fmt.Sscan(listID, &list)
+	code, _ = serve(t, nil, http.MethodPost, "api/lists/members", map[string]any{"list_id": list, "login": "bob" + suffix, "role": "admin"}, withCookie(alice))
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(t, nil, http.MethodPost, "api/lists/members", map[string]any{"list_id": list, "login": "bob" + suffix, "role": "viewer"}, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+
+	// viewer видит задачу, но не может ее менять
+	code, _ = serve(t, nil, http.MethodGet, "api/task?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code)
+	code, m = serve(t, nil, http.MethodGet, "api/tasks?search=Дежурство", nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+	code, _ = serve(t, nil, http.MethodPost, "api/task/done?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve(t, nil, http.MethodPut, "api/task", map[string]any{"id": id, "date": today, "title": "Изменено"}, withCookie(bob))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve(t, nil, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Новая", "list_id": listID}, withCookie(bob))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve(t, nil, http.MethodDelete, "api/task?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// не участник не видит ни задачу, ни список
+	code, _ = serve(t, nil, http.MethodGet, "api/task?id="+id, nil, withCookie(carol))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, nil, http.MethodDelete, "api/task?id="+id, nil, withCookie(carol))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, nil, http.MethodGet, "api/lists/members?id="+listID, nil, withCookie(carol))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// editor может выполнять задачи, но не управлять участниками
+	code, _ = serve(t, nil, http.MethodPost, "api/lists/members", map[string]any{"list_id": list, "login": "bob" + suffix, "role": "editor"}, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, nil, http.MethodPost, "api/task/done?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, nil, http.MethodPost, "api/lists/members", map[string]any{"list_id": list, "login": "carol" + suffix, "role": "viewer"}, withCookie(bob))
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, m = serve(t, nil, http.MethodGet, "api/lists/members?id="+listID, nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["members"], 2)
+
+	// последний владелец не может выйти из списка, участник может
+	members := m["members"].([]any)
+	var aliceID, bobID string
+	for _, item := range members {
+		member := item.(map[string]any)
+		if member["role"] == "owner" {
+			aliceID = fmt.Sprint(member["user_id"])
+		} else {
+			bobID = fmt.Sprint(member["user_id"])
+		}
+	}
+	code, _ = serve(t, nil, http.MethodDelete, "api/lists/members?id="+listID+"&user_id="+aliceID, nil, withCookie(alice))
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = serve(t, nil, http.MethodDelete, "api/lists/members?id="+listID+"&user_id="+bobID, nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, nil, http.MethodGet, "api/task?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = serve(t, nil, http.MethodDelete, "api/lists?id="+listID, nil, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestDeleteListTasks(t *testing.T) {
+	forEachRepository(t, testDeleteListTasks)
+}
+
+func testDeleteListTasks(t *testing.T, db *database.DB, repo repository.TaskRepository) {
+	lists := services.NewListService(db)
+	lists.Tasks = repo
+	tasks := services.NewTaskService(repo, time.Now)
+	today := time.Now().Format(`20060102`)
+
+	list, err := lists.CreateList(1, "Дом")
+	if !assert.NoError(t, err) {
+		return
+	}
+	listID := fmt.Sprint(list)
+	id, err := tasks.AddTask(1, models.Task{Date: today, Title: "Купить продукты", ListID: listID})
+	assert.NoError(t, err)
+	_, err = tasks.AddTask(1, models.Task{Date: today, Title: "Личная задача"})
+	assert.NoError(t, err)
+	assert.NoError(t, tasks.DoneTask(1, int(id), "", "", "", ""))
+	_, err = tasks.AddTask(1, models.Task{Date: today, Title: "Полить цветы", ListID: listID})
+	assert.NoError(t, err)
+
+	assert.NoError(t, lists.DeleteList(1, list))
+
+	// доступ к списку с тем же id возвращается напрямую в бд: задач и истории удаленного списка уже нет
+	_, err = db.Exec("INSERT INTO list_members (list_id, user_id, role) VALUES (?, ?, ?)", list, 1, models.RoleOwner)
+	assert.NoError(t, err)
+	found, err := repo.GetTasks(1, repository.TaskFilter{})
+	if assert.NoError(t, err) && assert.Len(t, found, 1) {
+		assert.Equal(t, "Личная задача", found[0].Title)
+	}
+	history, err := repo.GetHistory(1, repository.HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/server"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryServices - сервисы как в режиме --memory: без запущенного сервера и файла бд.
+func memoryServices(t *testing.T) server.Services {
+	db, err := database.OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return server.NewServices(db, repository.NewMemory(services.NewListService(db)))
+}
+
+// memoryRouter - обработчики API поверх memoryServices.
+func memoryRouter(t *testing.T) http.Handler {
+	return server.NewRouter(memoryServices(t), "../web")
+}
+
+// requestOption дополняет запрос serve, например токеном аутентификации.
+type requestOption func(*http.Request)
+
+func withCookie(token string) requestOption { // JWT в cookie, как у веб-интерфейса; пустой токен не передается
+	return func(req *http.Request) {
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "token", Value: token})
+		}
+	}
+}
+
+func withBearer(token string) requestOption { // JWT или личный API-токен в заголовке Authorization
+	return func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// serve выполняет запрос к router или, если router равен nil, к запущенному серверу (getURL).
+// body - значения для тела JSON или строка, которая передается как есть (text/plain).
+// Возвращает код ответа и тело JSON-объекта ответа.
+func serve(t *testing.T, router http.Handler, method string, apipath string, body any, options ...requestOption) (int, map[string]any) {
+	var reader io.Reader
+	contentType := "application/json"
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader, contentType = strings.NewReader(body), "text/plain"
+	default:
+		data, err := json.Marshal(body)
+		assert.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+
+	var req *http.Request
+	if router == nil {
+		var err error
+		req, err = http.NewRequest(method, getURL(apipath), reader)
+		if !assert.NoError(t, err) {
+			return 0, nil
+		}
+	} else {
+		req = httptest.NewRequest(method, "/"+apipath, reader)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for _, option := range options {
+		option(req)
+	}
+
+	code, data := 0, []byte(nil)
+	if router == nil {
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0, nil
+		}
+		defer resp.Body.Close()
+		data, err = io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		code = resp.StatusCode
+	} else {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		code, data = rec.Code, rec.Body.Bytes()
+	}
+	var m map[string]any
+	json.Unmarshal(data, &m)
+	return code, m
+}
+
+func TestMemoryTasks(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Полить цветы", "repeat": "d 3"})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+	assert.Equal(t, "1", id)
+
+	code, _ = serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": ""})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, m = serve(t, router, http.MethodGet, "api/tasks?search=цветы", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+
+	code, _ = serve(t, router, http.MethodPut, "api/task", map[string]any{"id": id, "date": today, "title": "Полить кактус", "repeat": "d 3"})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Полить кактус", m["title"])
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), m["date"])
+
+	code, _ = serve(t, router, http.MethodDelete, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// у каждого роутера свое хранилище
+	code, m = serve(t, memoryRouter(t), http.MethodGet, "api/tasks", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 0)
+}
+
+func TestMemoryLists(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "1")
+	t.Setenv("TODO_JWT_SECRET", "memory")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+
+	signUp := func(login string) string {
+		code, m := serve(t, router, http.MethodPost, "api/signup", map[string]any{"login": login, "password": "password123"})
+		assert.Equal(t, http.StatusOK, code)
+		token, _ := m["token"].(string)
+		return token
+	}
+	owner, viewer := signUp("owner"), signUp("viewer")
+
+	code, m := serve(t, router, http.MethodPost, "api/lists", map[string]any{"name": "Дом"}, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	list, listID := m["id"], fmt.Sprint(m["id"])
+	code, m = serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Купить продукты", "list_id": listID}, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	code, _ = serve(t, router, http.MethodGet, "api/task?id="+id, nil, withCookie(viewer))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = serve(t, router, http.MethodPost, "api/lists/members", map[string]any{"list_id": list, "login": "viewer", "role": "viewer"}, withCookie(owner))
+	assert.Equal(t, http.StatusOK, code)
+	code, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil, withCookie(viewer))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, listID, m["list_id"])
+	code, _ = serve(t, router, http.MethodDelete, "api/task?id="+id, nil, withCookie(viewer))
+	assert.Equal(t, http.StatusForbidden, code)
+}
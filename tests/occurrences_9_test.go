@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getOccurrences(t *testing.T, params url.Values) ([]string, map[string]any) {
+	body, err := requestJSON("api/occurrences?"+params.Encode(), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var list []string
+	if err = json.Unmarshal(body, &list); err == nil {
+		return list, nil
+	}
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return nil, m
+}
+
+func TestOccurrences(t *testing.T) {
+	tbl := []struct {
+		params url.Values
+		want   []string
+	}{
+		{url.Values{"date": {"20240126"}, "repeat": {"d 7"}, "now": {"20240126"}, "count": {"3"}},
+			[]string{"20240202", "20240209", "20240216"}},
+		{url.Values{"date": {"20240125"}, "repeat": {"w 1,3"}, "now": {"20240126"}, "until": {"20240207"}},
+			[]string{"20240129", "20240131", "20240205", "20240207"}},
+		{url.Values{"date": {"20240127"}, "repeat": {"m -1"}, "now": {"20240126"}, "count": {"3"}},
+			[]string{"20240131", "20240229", "20240331"}},
+		{url.Values{"date": {"20240126"}, "repeat": {"RRULE:FREQ=DAILY;COUNT=3"}, "now": {"20240126"}, "count": {"10"}},
+			[]string{"20240127", "20240128"}},
+		{url.Values{"date": {"20240126"}, "repeat": {"y"}, "now": {"20240126"}},
+			[]string{"20250126", "20260126", "20270126", "20280126", "20290126",
+				"20300126", "20310126", "20320126", "20330126", "20340126"}},
+	}
+	for _, v := range tbl {
+		list, m := getOccurrences(t, v.params)
+		assert.Nil(t, m, "Неожиданная ошибка для %v", v.params)
+		assert.Equal(t, v.want, list, "%v", v.params)
+	}
+
+	for _, params := range []url.Values{
+		{"date": {"20240126"}, "repeat": {"k 34"}},
+		{"date": {"20240126"}, "repeat": {""}},
+		{"date": {"ooops"}, "repeat": {"d 1"}},
+		{"date": {"20240126"}, "repeat": {"d 1"}, "count": {"abc"}},
+		{"date": {"20240126"}, "repeat": {"d 1"}, "until": {"01.02.2024"}},
+	} {
+		_, m := getOccurrences(t, params)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для %v", params)
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagination(t *testing.T) {
+	forEachRepository(t, testPagination)
+}
+
+func testPagination(t *testing.T, _ *database.DB, repo repository.TaskRepository) {
+	service := services.NewTaskService(repo, time.Now)
+	ids := []string{""} // номер задачи в списке -> id, задачи с единицы
+	for _, task := range []models.Task{
+		{Date: "20240103", Title: "Бета"},
+		{Date: "20240101", Title: "Альфа"},
+		{Date: "20240103", Title: "Альфа"},
+		{Date: "20240102", Title: "Гамма"},
+		{Date: "20240101", Title: "Бета"},
+		{Date: "20240103", Title: "Дельта"},
+		{Date: "20240102", Title: "Альфа"},
+	} {
+		id, err := repo.AddTask(1, task)
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(id))
+	}
+
+	tbl := []struct {
+		sort  string
+		order string
+		want  []int
+	}{
+		{"", "", []int{2, 5, 4, 7, 1, 3, 6}},
+		{"date", "desc", []int{6, 3, 1, 7, 4, 5, 2}},
+		{"title", "", []int{2, 3, 7, 1, 5, 4, 6}},
+		{"title", "desc", []int{6, 4, 5, 1, 7, 3, 2}},
+		{"created", "desc", []int{7, 6, 5, 4, 3, 2, 1}},
+	}
+	for _, v := range tbl {
+		var got []string
+		cursor, pages := "", 0
+		for {
+			// после первой страницы достаточно курсора: порядок записан в нем
+			params := services.TaskQuery{Cursor: cursor, Limit: 3}
+			if cursor == "" {
+				params.Sort, params.Order = v.sort, v.order
+			}
+			tasks, next, err := service.GetTasks(1, params)
+			if !assert.NoError(t, err) {
+				break
+			}
+			for _, task := range tasks {
+				got = append(got, task.ID)
+			}
+			pages++
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		want := make([]string, len(v.want))
+		for i, n := range v.want {
+			want[i] = ids[n]
+		}
+		assert.Equal(t, want, got, v.sort+" "+v.order)
+		assert.Equal(t, 3, pages, v.sort+" "+v.order)
+	}
+
+	// по релевантности курсор считает выданные задачи, строка поиска передается с каждой страницей
+	var got []string
+	cursor, relevance := "", ""
+	for {
+		tasks, next, err := service.GetTasks(1, services.TaskQuery{Search: "альфа", Cursor: cursor, Limit: 2})
+		if !assert.NoError(t, err) {
+			break
+		}
+		for _, task := range tasks {
+			got = append(got, task.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor, relevance = next, next
+	}
+	assert.Equal(t, []string{ids[2], ids[7], ids[3]}, got)
+
+	_, next, err := service.GetTasks(1, services.TaskQuery{Sort: "title", Limit: 6})
+	assert.NoError(t, err)
+	for _, params := range []services.TaskQuery{
+		{Sort: "duration"},
+		{Order: "up"},
+		{Limit: 100000},
+		{Cursor: "not-a-cursor"},
+		{Cursor: next, Sort: "date"},
+		{Cursor: relevance}, // курсор по релевантности без строки поиска
+	} {
+		_, _, err := service.GetTasks(1, params)
+		assert.ErrorIs(t, err, services.ErrInvalidFilter, params)
+	}
+}
+
+func TestPaginationAPI(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+	for i := 0; i < 3; i++ {
+		code, _ := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": fmt.Sprint("Задача ", i)})
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	code, m := serve(t, router, http.MethodGet, "api/tasks?limit=2&sort=created&order=desc", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 2)
+	next, _ := m["next_cursor"].(string)
+	if assert.NotEmpty(t, next) {
+		code, m = serve(t, router, http.MethodGet, "api/tasks?limit=2&cursor="+next, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, m["tasks"], 1)
+		assert.NotContains(t, m, "next_cursor")
+	}
+
+	code, _ = serve(t, router, http.MethodGet, "api/tasks?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(t, router, http.MethodGet, "api/tasks?cursor=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
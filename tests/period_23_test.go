@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/server"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskPeriods(t *testing.T) {
+	now := time.Date(2024, 2, 21, 15, 0, 0, 0, time.Local) // среда
+	service := services.NewTaskService(repository.NewMemory(nil), func() time.Time { return now })
+	for _, date := range []string{"20240219", "20240220", "20240221", "20240222", "20240225", "20240226", "20240227", "20240228"} {
+		_, err := service.Repo.AddTask(1, models.Task{Date: date, Title: "Задача " + date})
+		assert.NoError(t, err)
+	}
+
+	tbl := []struct {
+		params services.TaskQuery
+		dates  []string
+	}{
+		{services.TaskQuery{Period: "today"}, []string{"20240221"}},
+		{services.TaskQuery{Period: "Tomorrow"}, []string{"20240222"}},
+		{services.TaskQuery{Period: "this week"}, []string{"20240219", "20240220", "20240221", "20240222", "20240225"}},
+		{services.TaskQuery{Period: "overdue"}, []string{"20240219", "20240220"}},
+		{services.TaskQuery{Period: "next 7 days"}, []string{"20240221", "20240222", "20240225", "20240226", "20240227"}},
+		{services.TaskQuery{From: "22.02.2024", To: "20240226"}, []string{"20240222", "20240225", "20240226"}},
+		{services.TaskQuery{From: "today", Period: "this week"}, []string{"20240221", "20240222", "20240225"}},
+		{services.TaskQuery{To: "yesterday", Search: "after:19.02.2024"}, []string{"20240220"}},
+		{services.TaskQuery{From: "tomorrow", To: "yesterday"}, []string{}},
+	}
+	for _, v := range tbl {
+		tasks, _, err := service.GetTasks(1, v.params)
+		if assert.NoError(t, err, v.params) {
+			dates := []string{}
+			for _, task := range tasks {
+				dates = append(dates, task.Date)
+			}
+			assert.Equal(t, v.dates, dates, v.params)
+		}
+	}
+
+	for _, params := range []services.TaskQuery{{Period: "next week"}, {Period: "next 0 days"}, {From: "32.01.2024"}, {To: "someday"}} {
+		_, _, err := service.GetTasks(1, params)
+		assert.ErrorIs(t, err, services.ErrInvalidFilter, params)
+	}
+}
+
+func TestTaskPeriodsAPI(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+
+	code, _ := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Сегодня"})
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodPost, "api/task", map[string]any{"date": time.Now().AddDate(0, 0, 10).Format(`20060102`), "title": "Позже"})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, m := serve(t, router, http.MethodGet, "api/tasks?period="+url.QueryEscape("next 7 days"), nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+	code, m = serve(t, router, http.MethodGet, "api/tasks?from=tomorrow", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+
+	code, m = serve(t, router, http.MethodGet, "api/tasks?period=someday", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, m["error"])
+}
+
+func TestTaskClock(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	s := memoryServices(t)
+	s.Tasks.Now = func() time.Time { return time.Date(2024, 2, 21, 15, 0, 0, 0, time.Local) } // все обработчики задач по одним часам
+	router := server.NewRouter(s, "../web")
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": "today", "title": "Сегодня"})
+	assert.Equal(t, http.StatusOK, code)
+	today := fmt.Sprint(m["id"])
+	code, m = serve(t, router, http.MethodPost, "api/task", map[string]any{"date": "20240214", "title": "Отчет", "repeat": "d 5"})
+	assert.Equal(t, http.StatusOK, code)
+	weekly := fmt.Sprint(m["id"])
+
+	date := func(id string) any {
+		code, m := serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+		assert.Equal(t, http.StatusOK, code)
+		return m["date"]
+	}
+	assert.Equal(t, "20240221", date(today))
+	assert.Equal(t, "20240224", date(weekly)) // прошедшая дата пересчитана от 21.02.2024
+	code, m = serve(t, router, http.MethodGet, "api/tasks?period=today", nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+
+	code, _ = serve(t, router, http.MethodPost, "api/task/skip?id="+weekly, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "20240229", date(weekly))
+	code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+weekly, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "20240305", date(weekly))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/occurrences?date=20240214&repeat=d+5&count=2", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `["20240224", "20240229"]`, rec.Body.String())
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQueryErrors(t *testing.T) {
+	now := time.Date(2024, 2, 20, 12, 0, 0, 0, time.UTC)
+	tbl := []struct {
+		query    string
+		token    string
+		position int
+	}{
+		{"отчет before:32.01.2024", "before:32.01.2024", 6},
+		{"title:", "title:", 0},
+		{"отчет OR", "OR", 6},
+		{"OR отчет", "OR", 0},
+		{"отчет priority:high", "priority:high", 6},
+		{"repeat:maybe", "repeat:maybe", 0},
+		{`купить "хлеб молоко`, `"хлеб молоко`, 7},
+		{"купить -", "-", 7},
+		{"!!!", "!!!", 0},
+	}
+	for _, v := range tbl {
+		_, err := repository.ParseQuery(v.query, now)
+		var queryErr *repository.QueryError
+		if assert.ErrorAs(t, err, &queryErr, v.query) {
+			assert.Equal(t, v.token, queryErr.Token, v.query)
+			assert.Equal(t, v.position, queryErr.Position, v.query)
+		}
+	}
+
+	query, err := repository.ParseQuery("Встреча: after:today 20.02.2024 OR -repeat:yes", now)
+	assert.NoError(t, err)
+	assert.Len(t, query.Groups, 2)
+	if assert.Len(t, query.Groups[0], 3) {
+		assert.Equal(t, repository.Condition{Field: repository.FieldAfter, Value: "20240220"}, query.Groups[0][1])
+		assert.Equal(t, repository.Condition{Field: repository.FieldDate, Value: "20240220"}, query.Groups[0][2])
+	}
+	assert.Equal(t, []repository.Condition{{Field: repository.FieldRepeat, Negate: true, Value: "yes"}}, query.Groups[1])
+
+	query, err = repository.ParseQuery("  ", now)
+	assert.NoError(t, err)
+	assert.Nil(t, query)
+}
+
+func TestQuerySyntaxError(t *testing.T) {
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape("отчет before:вчера"), nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "before:вчера", m["token"])
+	assert.Equal(t, float64(6), m["position"])
+	assert.NotEmpty(t, m["error"])
+
+	code, _ := serve(t, nil, http.MethodGet, "api/tasks?search="+url.QueryEscape("repeat:yes OR -repeat:yes"), nil, withCookie(Token))
+	assert.Equal(t, http.StatusOK, code)
+}
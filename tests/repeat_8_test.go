@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatCanonical(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now().Format(`20060102`)
+
+	tbl := []struct {
+		repeat string
+		want   string
+	}{
+		{"d 07", "d 7"},
+		{"w 5,1,3,1", "w 1,3,5"},
+		{"m -1,07,-2,1 12,05", "m 1,7,-1,-2 5,12"},
+	}
+	for _, v := range tbl {
+		id := addTask(t, task{date: now, title: "Правило " + v.repeat, repeat: v.repeat})
+
+		var task Task
+		err := db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, task.Repeat)
+	}
+
+	for _, repeat := range []string{"d 0", "d 999", "m 31 2,4", "w 0",
+		"RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "RRULE:FREQ=MONTHLY;BYMONTH=4,6;BYMONTHDAY=-31"} {
+		m, err := postJSON("api/task", map[string]any{
+			"date":   now,
+			"title":  "Неверное правило",
+			"repeat": repeat,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0,
+			"Ожидается ошибка для правила %q", repeat)
+	}
+}
+
+func TestNextDateRRule(t *testing.T) {
+	tbl := []nextDate{
+		{"20240109", "RRULE:FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240126", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "RRULE:FREQ=DAILY;INTERVAL=10", "20240131"},
+		{"20240108", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "20240205"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240131", "RRULE:FREQ=MONTHLY", "20240331"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "20241128"},
+		{"20240101", "FREQ=WEEKLY;UNTIL=20240201", "20240129"},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=27", "20240127"},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=26", ""},
+		{"20240101", "RRULE:FREQ=DAILY;UNTIL=20240126", ""},
+		{"20240101", "RRULE:FREQ=HOURLY", ""},
+		{"20240101", "RRULE:FREQ=DAILY;BYDAY=2MO", ""},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=2;UNTIL=20240301", ""},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", ""},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=2,3;BYMONTHDAY=30", "20240330"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-29", "20240201"},
+	}
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`, v.date, v.repeat, v.want)
+	}
+}
+
+func TestDoneRRuleCount(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Три занятия подряд",
+		repeat: "RRULE:FREQ=DAILY;COUNT=3",
+	})
+
+	for i := 2; i > 0; i-- {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var task Task
+		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		now = now.AddDate(0, 0, 1)
+		assert.Equal(t, now.Format(`20060102`), task.Date)
+		assert.Equal(t, fmt.Sprintf("RRULE:FREQ=DAILY;COUNT=%d", i), task.Repeat)
+	}
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatEnd(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	for _, v := range []map[string]any{
+		{"date": today, "title": "Без правила", "repeat_count": 3},
+		{"date": today, "title": "Без правила", "repeat_until": "20991231"},
+		{"date": today, "title": "Отрицательное", "repeat": "d 7", "repeat_count": -1},
+		{"date": today, "title": "Неверная дата", "repeat": "d 7", "repeat_until": "31.12.2099"},
+		{"date": today, "title": "Дата в прошлом", "repeat": "d 7", "repeat_until": "20240101"},
+	} {
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для задачи %v", v)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":         today,
+		"title":        "Физиотерапия",
+		"repeat":       "d 2",
+		"repeat_count": 2,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), task.Date)
+	assert.Equal(t, int64(1), task.RepeatCount)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":         today,
+		"title":        "Зарядка до конца недели",
+		"repeat":       "d 3",
+		"repeat_until": now.AddDate(0, 0, 5).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id = fmt.Sprint(ret["id"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), task.Date)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
+
+func TestRepeatEndUpdate(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+	until := time.Now().AddDate(0, 0, 30).Format(`20060102`)
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{
+		"date": today, "title": "Физиотерапия", "repeat": "d 2", "repeat_count": 5,
+	})
+	assert.Equal(t, http.StatusOK, code)
+	counted := fmt.Sprint(m["id"])
+	code, m = serve(t, router, http.MethodPost, "api/task", map[string]any{
+		"date": today, "title": "Зарядка", "repeat": "d 1", "repeat_until": until,
+	})
+	assert.Equal(t, http.StatusOK, code)
+	limited := fmt.Sprint(m["id"])
+
+	// изменение из веб-интерфейса не делает серию бесконечной
+	for _, id := range []string{counted, limited} {
+		code, _ = serve(t, router, http.MethodPut, "api/task", map[string]any{"id": id, "date": today, "title": "Новое название", "comment": "", "repeat": "d 2"})
+		assert.Equal(t, http.StatusOK, code)
+	}
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+counted, nil)
+	assert.Equal(t, float64(5), m["repeat_count"])
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+limited, nil)
+	assert.Equal(t, until, m["repeat_until"])
+
+	code, _ = serve(t, router, http.MethodPut, "api/task", map[string]any{"id": limited, "date": today, "title": "Зарядка", "repeat": "d 2", "repeat_until": ""})
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+limited, nil)
+	assert.NotContains(t, m, "repeat_until")
+
+	code, _ = serve(t, router, http.MethodPut, "api/task", map[string]any{"id": counted, "date": today, "title": "Разовая", "repeat": ""})
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+counted, nil)
+	assert.NotContains(t, m, "repeat_count")
+}
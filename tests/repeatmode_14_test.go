@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatMode(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	m, err := postJSON("api/task", map[string]any{
+		"date":        day(0),
+		"title":       "Неизвестный режим",
+		"repeat":      "d 3",
+		"repeat_mode": "sometimes",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	tbl := []struct {
+		mode string
+		want string
+	}{
+		{"", day(8)},
+		{"schedule", day(8)},
+		{"completion", day(3)},
+	}
+	for _, v := range tbl {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":        day(5),
+			"title":       "Полить цветы",
+			"repeat":      "d 3",
+			"repeat_mode": v.mode,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var task map[string]string
+		assert.NoError(t, json.Unmarshal(body, &task))
+		if v.mode == "" {
+			assert.Equal(t, "schedule", task["repeat_mode"])
+		} else {
+			assert.Equal(t, v.mode, task["repeat_mode"])
+		}
+
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+
+		var done Task
+		err = db.Get(&done, `SELECT * FROM scheduler WHERE id=?`, id)
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, done.Date, "режим %q", v.mode)
+	}
+}
+
+func TestRepeatModeUpdate(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	today := time.Now().Format(`20060102`)
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{
+		"date": today, "title": "Полить цветы", "repeat": "d 3", "repeat_mode": "completion",
+	})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	// ни отсутствующий, ни пустой режим не меняют сохраненный
+	for _, task := range []map[string]any{
+		{"id": id, "date": today, "title": "Полить фикус", "comment": "", "repeat": "d 3"},
+		{"id": id, "date": today, "title": "Полить фикус", "repeat": "d 3", "repeat_mode": ""},
+	} {
+		code, _ = serve(t, router, http.MethodPut, "api/task", task)
+		assert.Equal(t, http.StatusOK, code)
+		_, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+		assert.Equal(t, "completion", m["repeat_mode"])
+	}
+
+	code, _ = serve(t, router, http.MethodPut, "api/task", map[string]any{"id": id, "date": today, "title": "Полить фикус", "repeat": "d 3", "repeat_mode": "schedule"})
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, "schedule", m["repeat_mode"])
+}
+
+func TestRepeatModeSkip(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	router := memoryRouter(t)
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(`20060102`) }
+
+	code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{
+		"date": day(5), "title": "Полить цветы", "repeat": "d 3", "repeat_mode": "completion",
+	})
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	// пропуск сдвигает от даты задачи и в режиме completion, выполнение - от текущего дня
+	code, _ = serve(t, router, http.MethodPost, "api/task/skip?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, day(8), m["date"])
+
+	code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, m = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, day(3), m["date"])
+}
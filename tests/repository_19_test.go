@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRepository проверяет одинаковое поведение TaskRepository на SQLite и в памяти.
+func TestRepository(t *testing.T) {
+	forEachRepository(t, testRepository)
+}
+
+// forEachRepository запускает test на SQLite во временном файле и на хранилище в памяти.
+func forEachRepository(t *testing.T, test func(t *testing.T, db *database.DB, repo repository.TaskRepository)) {
+	t.Run("sqlite", func(t *testing.T) {
+		t.Setenv("TODO_DBFILE", filepath.Join(t.TempDir(), "scheduler.db"))
+		testSQLRepository(t, test)
+	})
+	t.Run("memory", func(t *testing.T) {
+		db, err := database.OpenMemory() // только списки и участники, задачи в repository.NewMemory
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer db.Close()
+		test(t, db, repository.NewMemory(services.NewListService(db)))
+	})
+}
+
+func testSQLRepository(t *testing.T, test func(t *testing.T, db *database.DB, repo repository.TaskRepository)) {
+	db, err := database.Open()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	_, err = database.Migrate(db, false, io.Discard)
+	if !assert.NoError(t, err) {
+		return
+	}
+	test(t, db, repository.New(db))
+}
+
+func testRepository(t *testing.T, db *database.DB, repo repository.TaskRepository) {
+	author := time.Now().UnixNano()
+	stranger := author + 1
+	today := time.Now().Format(`20060102`)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	id, err := repo.AddTask(author, models.Task{Date: today, Title: "Contract " + fmt.Sprint(author), Repeat: "d 1",
+		RepeatCount: 3, Exdates: []string{today, tomorrow}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	taskID := int(id)
+
+	task, err := repo.GetTask(author, taskID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, task.RepeatCount)
+		assert.Equal(t, "", task.ListID)
+		assert.Equal(t, []string{today, tomorrow}, task.Exdates)
+	}
+	_, err = repo.GetTask(stranger, taskID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	tasks, err := repo.GetTasks(author, repository.TaskFilter{Query: parseQuery(t, "contract "+fmt.Sprint(author)), Limit: 10})
+	if assert.NoError(t, err) && assert.Len(t, tasks, 1) {
+		assert.Equal(t, fmt.Sprint(id), tasks[0].ID)
+	}
+	tasks, err = repo.GetTasks(stranger, repository.TaskFilter{Query: parseQuery(t, "date:"+today)})
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	role, err := repo.TaskRole(author, taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleOwner, role)
+	_, err = repo.TaskRole(stranger, taskID)
+	assert.EqualError(t, err, "task not found")
+
+	assert.EqualError(t, repo.UpdateTask(stranger, models.Task{ID: fmt.Sprint(id), Date: today, Title: "Чужая"}), "task not found")
+	assert.EqualError(t, repo.MoveTask(stranger, taskID, tomorrow, tomorrow, "d 1", true), "task not found")
+
+	// выполнение уменьшает количество повторений и убирает прошедшие исключения
+	assert.NoError(t, repo.MoveTask(author, taskID, tomorrow, today, "d 1", true))
+	task, err = repo.GetTask(author, taskID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, tomorrow, task.Date)
+		assert.Equal(t, today, task.SeriesDate)
+		assert.Equal(t, 2, task.RepeatCount)
+		assert.Equal(t, []string{tomorrow}, task.Exdates)
+	}
+
+	// участник общего списка с ролью viewer видит задачу, но не может ее менять
+	var listID int64
+	err = db.QueryRow("INSERT INTO lists (name) VALUES (?) RETURNING id", "Contract").Scan(&listID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = db.Exec("INSERT INTO list_members (list_id, user_id, role) VALUES (?, ?, ?), (?, ?, ?)",
+		listID, author, models.RoleOwner, listID, stranger, models.RoleViewer)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.UpdateTask(author, models.Task{ID: fmt.Sprint(id), Date: tomorrow, Title: "В списке", ListID: fmt.Sprint(listID)}))
+
+	task, err = repo.GetTask(stranger, taskID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, fmt.Sprint(listID), task.ListID)
+		assert.Empty(t, task.Exdates)
+	}
+	role, err = repo.ListRole(stranger, listID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleViewer, role)
+	assert.EqualError(t, repo.DeleteTask(stranger, taskID), "task not found")
+
+	assert.NoError(t, repo.DeleteTask(author, taskID))
+	_, err = repo.GetTask(author, taskID)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	testRepositorySearch(t, repo, author, today)
+}
+
+func testRepositorySearch(t *testing.T, repo repository.TaskRepository, userID int64, today string) {
+	bread, err := repo.AddTask(userID, models.Task{Date: today, Title: "Купить хлеб", Comment: "в магазине у дома"})
+	assert.NoError(t, err)
+	gift, err := repo.AddTask(userID, models.Task{Date: today, Title: "Позвонить сестре", Comment: "купить подарок маме"})
+	assert.NoError(t, err)
+
+	search := func(query string) []models.Task {
+		tasks, err := repo.GetTasks(userID, repository.TaskFilter{Query: parseQuery(t, query), Limit: 10})
+		assert.NoError(t, err)
+		return tasks
+	}
+	ids := func(tasks []models.Task) []string {
+		ids := []string{}
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+	tasks := search("КУПИ") // префикс, регистр кириллицы не важен, совпадение в заголовке выше
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, fmt.Sprint(bread), tasks[0].ID)
+		assert.Equal(t, fmt.Sprint(gift), tasks[1].ID)
+		assert.Contains(t, tasks[0].Snippet, "<mark>Купить</mark>")
+		assert.Contains(t, tasks[1].Snippet, "<mark>купить</mark>")
+	}
+	tasks = search(`"купить подарок" маме`)
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, fmt.Sprint(gift), tasks[0].ID)
+	}
+	assert.Empty(t, search(`"купить маме"`))
+	assert.Empty(t, search("купить торт"))
+
+	breadID, giftID := fmt.Sprint(bread), fmt.Sprint(gift)
+	assert.Equal(t, []string{giftID}, ids(search("купить -хлеб")))
+	assert.Equal(t, []string{breadID}, ids(search("title:купить")))
+	assert.Equal(t, []string{breadID, giftID}, ids(search("comment:подарок OR хлеб")))
+	assert.Equal(t, []string{breadID, giftID}, ids(search("купить repeat:no before:tomorrow")))
+	assert.Empty(t, search("купить repeat:yes"))
+	assert.Empty(t, search("купить after:today"))
+	assert.Equal(t, []string{giftID}, ids(search("-title:хлеб date:"+today+" купить")))
+
+	// текст задачи экранируется, разметка во фрагменте - только <mark>
+	_, err = repo.AddTask(userID, models.Task{Date: today, Title: `<img src=x onerror="alert(1)"> торт`})
+	assert.NoError(t, err)
+	if tasks := search("торт"); assert.Len(t, tasks, 1) {
+		assert.Equal(t, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>торт</mark>", tasks[0].Snippet)
+	}
+}
+
+func parseQuery(t *testing.T, search string) *repository.Query {
+	query, err := repository.ParseQuery(search, time.Now())
+	assert.NoError(t, err)
+	return query
+}
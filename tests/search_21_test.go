@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFullTextSearch(t *testing.T) {
+	if !Search {
+		t.Skip("поиск отключен в settings.go")
+	}
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	id := addTask(t, task{date: today, title: "Записаться к стоматологу", comment: "Клиника на Садовой"})
+
+	tasks := getTasks(t, url.QueryEscape("СТОМАТОЛ"))
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, id, tasks[0]["id"])
+		assert.Equal(t, "Записаться к <mark>стоматологу</mark>", tasks[0]["snippet"])
+	}
+	tasks = getTasks(t, url.QueryEscape(`"клиника на садовой"`))
+	assert.Len(t, tasks, 1)
+
+	// индекс обновляется триггерами и при изменении задачи в обход API
+	_, err := db.Exec("UPDATE scheduler SET comment = 'Клиника на Тверской' WHERE id = ?", id)
+	assert.NoError(t, err)
+	assert.Empty(t, getTasks(t, url.QueryEscape("садовой")))
+	assert.Len(t, getTasks(t, url.QueryEscape("тверск")), 1)
+
+	_, err = db.Exec("DELETE FROM scheduler WHERE id = ?", id)
+	assert.NoError(t, err)
+	assert.Empty(t, getTasks(t, url.QueryEscape("стоматолог")))
+}
@@ -2,7 +2,7 @@ package tests
 
 var Port = 7540
 var DBFile = "../scheduler.db"
-var FullNextDate = false
+var FullNextDate = true
 var Search = true
 var Token = ``
 var TaskLimit = 50
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignIn(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	t.Setenv("TODO_JWT_SECRET", "")
+	router := memoryRouter(t)
+	get := func(apipath string, token string) int {
+		code, _ := serve(t, router, http.MethodGet, apipath, nil, withCookie(token))
+		return code
+	}
+
+	// без пароля аутентификация отключена
+	code, m := serve(t, router, http.MethodPost, "api/signin", map[string]any{"password": "password"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, m["error"])
+	assert.Equal(t, http.StatusOK, get("api/tasks", ""))
+
+	t.Setenv("TODO_PASSWORD", "password")
+	code, m = serve(t, router, http.MethodPost, "api/signin", map[string]any{"password": "wrong password"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.NotEmpty(t, m["error"])
+	assert.Empty(t, m["token"])
+
+	code, m = serve(t, router, http.MethodPost, "api/signin", map[string]any{"password": "password"})
+	assert.Equal(t, http.StatusOK, code)
+	token, _ := m["token"].(string)
+	assert.NotEmpty(t, token)
+
+	// в токене нет ничего, по чему можно подобрать пароль без ключа подписи
+	parts := strings.Split(token, ".")
+	if assert.Len(t, parts, 3) {
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+		sum := sha256.Sum256([]byte("password"))
+		assert.NotContains(t, string(payload), hex.EncodeToString(sum[:]))
+		assert.NotContains(t, string(payload), "password")
+	}
+
+	assert.Equal(t, http.StatusOK, get("api/tasks", token))
+	assert.Equal(t, http.StatusUnauthorized, get("api/tasks", ""))
+	assert.Equal(t, http.StatusUnauthorized, get("api/tasks", token+"x"))
+	assert.Equal(t, http.StatusOK, get("api/nextdate?now=20240126&date=20240126&repeat=d+1", ""))
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withBearer(token))
+	assert.Equal(t, http.StatusOK, code)
+
+	// после смены пароля старые токены не принимаются
+	t.Setenv("TODO_PASSWORD", "new password")
+	assert.Equal(t, http.StatusUnauthorized, get("api/tasks", token))
+	code, m = serve(t, router, http.MethodPost, "api/signin", map[string]any{"password": "new password"})
+	assert.Equal(t, http.StatusOK, code)
+	token, _ = m["token"].(string)
+	assert.Equal(t, http.StatusOK, get("api/tasks", token))
+}
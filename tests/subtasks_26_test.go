@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/models"
+	"github.com/rust2014/go_final_project/repository"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtasks(t *testing.T) {
+	forEachRepository(t, testSubtasks)
+}
+
+func testSubtasks(t *testing.T, _ *database.DB, repo repository.TaskRepository) {
+	service := services.NewTaskService(repo, time.Now)
+	today := time.Now().Format(`20060102`)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	add := func(task models.Task) int {
+		id, err := service.AddTask(1, task)
+		assert.NoError(t, err, task.Title)
+		return int(id)
+	}
+	release := add(models.Task{Date: today, Title: "Релиз", Repeat: "d 7", Checklist: []models.ChecklistItem{
+		{Text: " Собрать "}, {Text: "Проверить"}, {Text: "Выложить"},
+	}})
+	build := add(models.Task{Date: today, Title: "Сборка", ParentID: fmt.Sprint(release)})
+	later := add(models.Task{Date: tomorrow, Title: "Анонс", ParentID: fmt.Sprint(release)})
+	step := add(models.Task{Date: today, Title: "Тесты", ParentID: fmt.Sprint(build)})
+	add(models.Task{Date: tomorrow, Title: "Дайджест", Repeat: "d 7", ParentID: fmt.Sprint(release)})
+
+	_, err := service.AddTask(1, models.Task{Date: today, Title: "Чужая", ParentID: "100500"})
+	assert.ErrorIs(t, err, services.ErrInvalidParent)
+	_, err = service.AddTask(2, models.Task{Date: today, Title: "Чужая", ParentID: fmt.Sprint(release)})
+	assert.ErrorIs(t, err, services.ErrInvalidParent)
+
+	// задача не может стать подзадачей самой себя или своей подзадачи
+	task, err := service.GetTask(1, release)
+	if assert.NoError(t, err) {
+		task.ParentID = fmt.Sprint(step)
+		assert.ErrorIs(t, service.UpdateTask(1, *task), services.ErrInvalidParent)
+		task.ParentID = task.ID
+		assert.ErrorIs(t, service.UpdateTask(1, *task), services.ErrInvalidParent)
+	}
+
+	tasks, _, err := service.GetTasks(1, services.TaskQuery{Parent: fmt.Sprint(release), Sort: "created"})
+	if assert.NoError(t, err) && assert.Len(t, tasks, 3) {
+		assert.Equal(t, fmt.Sprint(build), tasks[0].ID)
+		assert.Equal(t, fmt.Sprint(release), tasks[0].ParentID)
+	}
+	_, _, err = service.GetTasks(1, services.TaskQuery{Parent: "abc"})
+	assert.ErrorIs(t, err, services.ErrInvalidFilter)
+
+	// открыты разовые подзадачи, даже более поздние, и повторяющиеся не позже даты родителя
+	if assert.NotNil(t, task) {
+		task.ParentID = ""
+		open, err := service.OpenSubtasks(1, task)
+		if assert.NoError(t, err) && assert.Len(t, open, 2) {
+			assert.Equal(t, fmt.Sprint(build), open[0].ID)
+			assert.Equal(t, fmt.Sprint(later), open[1].ID)
+		}
+	}
+
+	// чек-лист: добавление, отметка, порядок, удаление
+	task, err = service.GetTask(1, release)
+	if !assert.NoError(t, err) || !assert.Len(t, task.Checklist, 3) {
+		return
+	}
+	assert.Equal(t, "Собрать", task.Checklist[0].Text)
+	ids := []int64{task.Checklist[0].ID, task.Checklist[1].ID, task.Checklist[2].ID}
+	added, err := service.AddChecklistItem(1, release, "Отметить в трекере")
+	assert.NoError(t, err)
+	_, err = service.AddChecklistItem(1, release, "  ")
+	assert.Error(t, err)
+	done, text := true, "Проверить на стенде"
+	assert.NoError(t, service.UpdateChecklistItem(1, release, ids[1], &text, &done))
+	assert.NoError(t, service.UpdateChecklistItem(1, release, ids[0], nil, &done))
+	assert.ErrorIs(t, service.UpdateChecklistItem(1, build, ids[0], nil, &done), repository.ErrItemNotFound)
+	assert.NoError(t, service.ReorderChecklist(1, release, []int64{added, ids[2], ids[1], ids[0]}))
+	assert.ErrorIs(t, service.ReorderChecklist(1, release, []int64{added, ids[2]}), services.ErrChecklistOrder)
+	assert.NoError(t, service.DeleteChecklistItem(1, release, ids[2]))
+	assert.ErrorIs(t, service.DeleteChecklistItem(1, release, ids[2]), repository.ErrItemNotFound)
+	_, err = service.AddChecklistItem(2, release, "Чужой пункт")
+	assert.Error(t, err)
+
+	task, err = service.GetTask(1, release)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []models.ChecklistItem{
+			{ID: added, Text: "Отметить в трекере"},
+			{ID: ids[1], Text: "Проверить на стенде", Done: true},
+			{ID: ids[0], Text: "Собрать", Done: true},
+		}, task.Checklist)
+	}
+
+	// выполнение повторяющейся задачи сбрасывает отметки, пропуск - нет
+	assert.NoError(t, service.SkipTask(1, release, tomorrow, "", "d 7"))
+	task, err = service.GetTask(1, release)
+	if assert.NoError(t, err) {
+		assert.True(t, task.Checklist[1].Done)
+	}
+	assert.NoError(t, service.DoneTask(1, release, time.Now().AddDate(0, 0, 8).Format(`20060102`), "", "d 7", ""))
+	task, err = service.GetTask(1, release)
+	if assert.NoError(t, err) {
+		for _, item := range task.Checklist {
+			assert.False(t, item.Done, item.Text)
+		}
+		assert.Len(t, task.Checklist, 3)
+	}
+
+	// после удаления родителя подзадачи становятся задачами верхнего уровня
+	assert.NoError(t, service.DeleteTask(1, release))
+	for _, id := range []int{build, later} {
+		task, err := service.GetTask(1, id)
+		if assert.NoError(t, err) {
+			assert.Empty(t, task.ParentID)
+		}
+	}
+	task, err = service.GetTask(1, step)
+	if assert.NoError(t, err) {
+		assert.Equal(t, strconv.Itoa(build), task.ParentID)
+	}
+}
+
+func TestSubtasksAPI(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	today := time.Now().Format(`20060102`)
+
+	addTasks := func(router http.Handler) (string, string) { // повторяющийся родитель с чек-листом и подзадача
+		code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Релиз", "repeat": "d 7",
+			"checklist": []map[string]any{{"text": "Собрать"}}})
+		assert.Equal(t, http.StatusOK, code)
+		parent := fmt.Sprint(m["id"])
+		code, m = serve(t, router, http.MethodPost, "api/task", map[string]any{"date": today, "title": "Сборка", "parent_id": parent})
+		assert.Equal(t, http.StatusOK, code)
+		return parent, fmt.Sprint(m["id"])
+	}
+
+	t.Run("block", func(t *testing.T) {
+		router := memoryRouter(t)
+		parent, child := addTasks(router)
+
+		code, m := serve(t, router, http.MethodGet, "api/task?id="+parent, nil)
+		assert.Equal(t, http.StatusOK, code)
+		checklist, _ := m["checklist"].([]any)
+		if !assert.Len(t, checklist, 1) {
+			return
+		}
+		item := fmt.Sprint(checklist[0].(map[string]any)["id"])
+		code, _ = serve(t, router, http.MethodPut, "api/task/checklist", map[string]any{"task_id": parent, "id": checklist[0].(map[string]any)["id"], "done": true})
+		assert.Equal(t, http.StatusOK, code)
+		code, m = serve(t, router, http.MethodPost, "api/task/checklist", map[string]any{"task_id": parent, "text": "Выложить"})
+		assert.Equal(t, http.StatusOK, code)
+		code, _ = serve(t, router, http.MethodPut, "api/task/checklist/order", map[string]any{"task_id": parent, "ids": []any{m["id"], checklist[0].(map[string]any)["id"]}})
+		assert.Equal(t, http.StatusOK, code)
+		code, _ = serve(t, router, http.MethodPut, "api/task/checklist/order", map[string]any{"task_id": parent, "ids": []any{m["id"]}})
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = serve(t, router, http.MethodDelete, "api/task/checklist?task_id="+parent+"&id=100500", nil)
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = serve(t, router, http.MethodPost, "api/task/checklist", map[string]any{"task_id": "100500", "text": "Пункт"})
+		assert.Equal(t, http.StatusNotFound, code)
+
+		code, m = serve(t, router, http.MethodPost, "api/task/done?id="+parent, nil)
+		assert.Equal(t, http.StatusConflict, code)
+		assert.Equal(t, []any{child}, m["subtasks"])
+
+		code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+child, nil)
+		assert.Equal(t, http.StatusOK, code)
+		code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+parent, nil)
+		assert.Equal(t, http.StatusOK, code)
+
+		code, m = serve(t, router, http.MethodGet, "api/task?id="+parent, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEqual(t, today, m["date"])
+		for _, item := range m["checklist"].([]any) {
+			assert.Equal(t, false, item.(map[string]any)["done"])
+		}
+		assert.Equal(t, "Выложить", m["checklist"].([]any)[0].(map[string]any)["text"])
+
+		code, _ = serve(t, router, http.MethodDelete, "api/task/checklist?task_id="+parent+"&id="+item, nil)
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		router := memoryRouter(t)
+		parent, child := addTasks(router)
+
+		// изменение из веб-интерфейса без parent_id оставляет подзадачу у родителя
+		task := map[string]any{"id": child, "date": today, "title": "Сборка релиза", "comment": "", "repeat": ""}
+		code, _ := serve(t, router, http.MethodPut, "api/task", task)
+		assert.Equal(t, http.StatusOK, code)
+		_, m := serve(t, router, http.MethodGet, "api/task?id="+child, nil)
+		assert.Equal(t, parent, m["parent_id"])
+
+		task["parent_id"] = "0"
+		code, _ = serve(t, router, http.MethodPut, "api/task", task)
+		assert.Equal(t, http.StatusOK, code)
+		_, m = serve(t, router, http.MethodGet, "api/task?id="+child, nil)
+		assert.NotContains(t, m, "parent_id")
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		t.Setenv("TODO_SUBTASK_DONE", "cascade")
+		router := memoryRouter(t)
+		parent, child := addTasks(router)
+		tomorrow := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+		code, m := serve(t, router, http.MethodPost, "api/task", map[string]any{"date": tomorrow, "title": "Анонс", "parent_id": parent})
+		assert.Equal(t, http.StatusOK, code)
+		later := fmt.Sprint(m["id"])
+
+		code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+parent, nil)
+		assert.Equal(t, http.StatusOK, code)
+		for _, id := range []string{child, later} { // разовая подзадача позже родителя тоже выполняется
+			code, _ = serve(t, router, http.MethodGet, "api/task?id="+id, nil)
+			assert.Equal(t, http.StatusNotFound, code)
+		}
+		code, m = serve(t, router, http.MethodGet, "api/tasks?parent="+parent, nil)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, m["tasks"])
+	})
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokens(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "password")
+	t.Setenv("TODO_MULTIUSER", "")
+	t.Setenv("TODO_JWT_SECRET", "")
+	router := memoryRouter(t)
+
+	code, m := serve(t, router, http.MethodPost, "api/signin", map[string]any{"password": "password"})
+	assert.Equal(t, http.StatusOK, code)
+	session, _ := m["token"].(string)
+	code, _ = serve(t, router, http.MethodPost, "api/tokens", map[string]any{"name": "cron"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, m = serve(t, router, http.MethodPost, "api/tokens", map[string]any{"name": "cron"}, withCookie(session))
+	assert.Equal(t, http.StatusOK, code)
+	full, _ := m["token"].(string)
+	assert.NotEmpty(t, full)
+	fullID := fmt.Sprint(m["id"])
+
+	code, m = serve(t, router, http.MethodPost, "api/tokens", map[string]any{"name": "monitoring", "read_only": true}, withCookie(session))
+	assert.Equal(t, http.StatusOK, code)
+	readOnly, _ := m["token"].(string)
+	readOnlyID := fmt.Sprint(m["id"])
+
+	task := map[string]any{"date": time.Now().Format(`20060102`), "title": "Из скрипта"}
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withBearer(readOnly))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodPost, "api/task", task, withBearer(readOnly))
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = serve(t, router, http.MethodPost, "api/task", task, withBearer(full))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withBearer(full+"0"))
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, m = serve(t, router, http.MethodGet, "api/tokens", nil, withCookie(session))
+	assert.Equal(t, http.StatusOK, code)
+	tokens, _ := m["tokens"].([]any)
+	assert.Len(t, tokens, 2)
+	for _, item := range tokens {
+		token := item.(map[string]any)
+		assert.Nil(t, token["token"]) // сам токен в списке не возвращается
+		if fmt.Sprint(token["id"]) == readOnlyID {
+			assert.Equal(t, true, token["read_only"])
+			assert.Equal(t, "monitoring", token["name"])
+			assert.NotEmpty(t, token["last_used"])
+		}
+	}
+
+	code, _ = serve(t, router, http.MethodDelete, "api/tokens?id="+fullID, nil, withCookie(session))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodDelete, "api/tokens?id="+fullID, nil, withCookie(session))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withBearer(full))
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// личный токен не зависит от пароля, JWT после смены пароля перестает действовать
+	t.Setenv("TODO_PASSWORD", "new password")
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withBearer(readOnly))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodGet, "api/tokens", nil, withCookie(session))
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = serve(t, router, http.MethodDelete, "api/tokens?id="+readOnlyID, nil, withBearer(full))
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
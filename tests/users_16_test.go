@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rust2014/go_final_project/database"
+	"github.com/rust2014/go_final_project/server"
+	"github.com/rust2014/go_final_project/services"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func signUp(t *testing.T, router http.Handler, login string) string { // регистрация через router или запущенный сервер (nil)
+	code, m := serve(t, router, http.MethodPost, "api/signup", map[string]any{"login": login, "password": "secret1"})
+	assert.Equal(t, http.StatusOK, code)
+	token, _ := m["token"].(string)
+	assert.NotEmpty(t, token)
+	return token
+}
+
+func TestUsers(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "")
+	t.Setenv("TODO_MULTIUSER", "")
+	t.Setenv("TODO_JWT_SECRET", "")
+	s := memoryServices(t)
+	router := server.NewRouter(s, "../web")
+
+	// в однопользовательском режиме регистрации нет
+	code, _ := serve(t, router, http.MethodPost, "api/signup", map[string]any{"login": "alice", "password": "secret1"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	t.Setenv("TODO_MULTIUSER", "1")
+	alice := signUp(t, router, "alice")
+	bob := signUp(t, router, "bob")
+
+	code, _ = serve(t, router, http.MethodPost, "api/signup", map[string]any{"login": "alice", "password": "secret2"})
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = serve(t, router, http.MethodPost, "api/signup", map[string]any{"login": "carol", "password": "123"})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serve(t, router, http.MethodPost, "api/signin", map[string]any{"login": "alice", "password": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, m := serve(t, router, http.MethodPost, "api/signin", map[string]any{"login": "alice", "password": "secret1"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, m["token"])
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, m = serve(t, router, http.MethodPost, "api/task", map[string]any{
+		"date":  time.Now().Format(`20060102`),
+		"title": "Задача Алисы",
+	}, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+	id := fmt.Sprint(m["id"])
+
+	code, _ = serve(t, router, http.MethodGet, "api/task?id="+id, nil, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodGet, "api/task?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, m = serve(t, router, http.MethodGet, "api/tasks?search=Алисы", nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, m["tasks"])
+	code, m = serve(t, router, http.MethodGet, "api/tasks?search=Алисы", nil, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, m["tasks"], 1)
+
+	code, _ = serve(t, router, http.MethodPut, "api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Чужая задача",
+	}, withCookie(bob))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, router, http.MethodPost, "api/task/done?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(t, router, http.MethodDelete, "api/task?id="+id, nil, withCookie(bob))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = serve(t, router, http.MethodDelete, "api/task?id="+id, nil, withCookie(alice))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(t, router, http.MethodGet, "api/task?id="+id, nil, withCookie(alice))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// после смены пароля старые токены пользователя не принимаются
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret2"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	_, err = s.Users.DB.Exec("UPDATE users SET password_hash = ? WHERE login = ?", string(hash), "alice")
+	assert.NoError(t, err)
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withCookie(alice))
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code)
+
+	// без многопользовательского режима токены пользователей не действуют
+	t.Setenv("TODO_MULTIUSER", "")
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withCookie(bob))
+	assert.Equal(t, http.StatusOK, code) // аутентификация отключена: задачи администратора
+	t.Setenv("TODO_PASSWORD", "password")
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withCookie(bob))
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestSigningKey(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "admin-password")
+	t.Setenv("TODO_MULTIUSER", "1")
+	t.Setenv("TODO_JWT_SECRET", "")
+
+	db, err := database.OpenMemory()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	users := services.NewUserService(db)
+	key, err := users.SigningKey()
+	assert.NoError(t, err)
+	assert.Len(t, key, 32)
+	again, err := users.SigningKey() // ключ сохраняется в бд и не меняется
+	assert.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	// токен пользователя не подписан паролем администратора
+	router := memoryRouter(t)
+	code, m := serve(t, router, http.MethodPost, "api/signup", map[string]any{"login": "alice", "password": "secret1"})
+	assert.Equal(t, http.StatusOK, code)
+	token, _ := m["token"].(string)
+	_, err = jwt.Parse(token, func(*jwt.Token) (any, error) { return []byte("admin-password"), nil })
+	assert.ErrorIs(t, err, jwt.ErrSignatureInvalid)
+	code, _ = serve(t, router, http.MethodGet, "api/tasks", nil, withCookie(token))
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -2,21 +2,184 @@ package validation
 
 import (
 	"errors"
-	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rust2014/go_final_project/dates"
+	"github.com/rust2014/go_final_project/models"
 )
 
 func ValidateRepeatRule(repeat string) error { // проверяет формат правила повторения
-	var (
-		dayPattern  = regexp.MustCompile(`^d\s\d+$`)
-		yearPattern = regexp.MustCompile(`^y$`)
-	)
+	if repeat == "" {
+		return nil
+	}
+
+	if _, err := dates.ParseRule(repeat); err != nil { // тот же разбор, что и в dates.NextDate
+		return errors.New("the repetition rule is in the wrong format")
+	}
+	return nil
+}
 
+func NormalizeRepeatRule(repeat string) (string, error) { // приводит правило повторения к каноническому виду для хранения
 	if repeat == "" {
+		return "", nil
+	}
+
+	rule, err := dates.ParseRule(repeat)
+	if err != nil {
+		return "", errors.New("the repetition rule is in the wrong format")
+	}
+	return rule.String(), nil
+}
+
+func ValidateRepeatEnd(date, repeat, until string, count int) error { // проверяет условия окончания серии повторений
+	if until == "" && count == 0 {
 		return nil
 	}
+	if repeat == "" {
+		return errors.New("the end of repetition is set without a repetition rule")
+	}
+	if count < 0 {
+		return errors.New("the number of repetitions cannot be negative")
+	}
+	if until != "" {
+		if _, err := time.Parse(dates.DefaultDateFormat, until); err != nil {
+			return errors.New("the repeat until date is in the wrong format")
+		}
+		if until < date {
+			return errors.New("the repeat until date is before the task date")
+		}
+	}
+	return nil
+}
+
+func NormalizeExdates(repeat string, exdates []string) ([]string, error) { // проверяет исключенные даты, убирает повторы и сортирует
+	if len(exdates) == 0 {
+		return nil, nil
+	}
+	if repeat == "" {
+		return nil, errors.New("excluded dates are set without a repetition rule")
+	}
+	seen := make(map[string]bool, len(exdates))
+	var result []string
+	for _, date := range exdates {
+		if _, err := time.Parse(dates.DefaultDateFormat, date); err != nil {
+			return nil, errors.New("the excluded date is in the wrong format")
+		}
+		if !seen[date] {
+			seen[date] = true
+			result = append(result, date)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func NormalizeRepeatMode(mode string) (string, error) { // режим повторения, по умолчанию по расписанию
+	switch mode {
+	case "", models.RepeatModeSchedule:
+		return models.RepeatModeSchedule, nil
+	case models.RepeatModeCompletion:
+		return mode, nil
+	}
+	return "", errors.New("the repeat mode is unknown")
+}
+
+func ValidatePriority(priority int) error {
+	if priority < 0 || priority > models.MaxPriority {
+		return errors.New("the priority must be from 1 to 4")
+	}
+	return nil
+}
+
+func NormalizeTag(tag string) (string, error) { // метка в нижнем регистре без #: буквы, цифры, - и _
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" || utf8.RuneCountInString(tag) > models.MaxTagLen {
+		return "", errors.New("the tag must be from 1 to 32 characters long")
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", errors.New("the tag may contain only letters, digits, - and _")
+		}
+	}
+	return tag, nil
+}
 
-	if !dayPattern.MatchString(repeat) && !yearPattern.MatchString(repeat) { // проверка на соответствие правилу повторения
-		return errors.New("the repetition rule is in the wrong format") // если оба правила не совпадают
+func NormalizeTags(tags []string) ([]string, error) { // проверяет метки, убирает повторы и сортирует
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	if len(result) > models.MaxTags {
+		return nil, errors.New("too many tags")
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func ValidateTime(clock string, duration int) error { // время начала 15:04 и длительность в минутах
+	if clock == "" {
+		if duration != 0 {
+			return errors.New("the duration is set without a time")
+		}
+		return nil
+	}
+	if _, err := time.Parse("15:04", clock); err != nil || len(clock) != 5 {
+		return errors.New("the time is in the wrong format")
+	}
+	if duration < 0 || duration > models.MaxDuration {
+		return errors.New("the duration must be from 1 to 1440 minutes")
 	}
 	return nil
 }
+
+func NormalizeChecklistText(text string) (string, error) { // текст пункта чек-листа без пробелов по краям
+	text = strings.TrimSpace(text)
+	if text == "" || !IsValidJSON(text) {
+		return "", errors.New("the checklist item text is empty or incorrect")
+	}
+	if utf8.RuneCountInString(text) > models.MaxChecklistText {
+		return "", errors.New("the checklist item text is too long")
+	}
+	return text, nil
+}
+
+func NormalizeChecklist(items []models.ChecklistItem) ([]models.ChecklistItem, error) { // пункты новой задачи
+	if len(items) > models.MaxChecklistItems {
+		return nil, errors.New("too many checklist items")
+	}
+	var result []models.ChecklistItem
+	for _, item := range items {
+		text, err := NormalizeChecklistText(item.Text)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, models.ChecklistItem{Text: text, Done: item.Done})
+	}
+	return result, nil
+}
+
+func NormalizeNote(note string) (string, error) { // заметка к выполнению задачи, может быть пустой
+	note = strings.TrimSpace(note)
+	if !IsValidJSON(note) {
+		return "", errors.New("incorrect characters in the note")
+	}
+	if utf8.RuneCountInString(note) > models.MaxNoteLen {
+		return "", errors.New("the note is too long")
+	}
+	return note, nil
+}